@@ -0,0 +1,245 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Archived tasks live in one file per year, archive/<list>/archive-YYYY.json
+// next to the store, in the format of the store file. A task is filed under
+// the year of its day. The files are read the first time they are asked for.
+
+var archiveFilePattern = regexp.MustCompile(`^archive-(\d{4})\.json$`)
+
+func (s *Store) archiveDir() string {
+	return filepath.Join(filepath.Dir(s.path), "archive", ListName(s.path))
+}
+
+func (s *Store) archivePath(year int) string {
+	return filepath.Join(s.archiveDir(), fmt.Sprintf("archive-%d.json", year))
+}
+
+// Archive moves the tasks scheduled and completed before the given time
+// into their archive files.
+func (s *Store) Archive(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.beginUnsafe(); err != nil {
+		return 0, err
+	}
+	defer s.unlockUnsafe()
+	return s.archiveUnsafe(before)
+}
+
+// archiveUnsafe writes the archive files before the journal entries that
+// drop the tasks from the store, so a crash in between leaves the tasks in
+// both places rather than in neither; archiving them again is harmless.
+func (s *Store) archiveUnsafe(before time.Time) (int, error) {
+	byYear := map[int][]*Task{}
+	for _, t := range s.tasks {
+		if t.IsCompleted() && t.Date.Before(before) && t.CompletedAt.Before(before) {
+			byYear[t.Date.Year()] = append(byYear[t.Date.Year()], t)
+		}
+	}
+	if len(byYear) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	var entries []journalEntry
+	for year, tasks := range byYear {
+		archived, err := s.readArchiveUnsafe(year)
+		if err != nil {
+			return 0, err
+		}
+		merged := append([]*Task(nil), archived...)
+		for _, t := range tasks {
+			merged = upsertTask(merged, cloneTask(t))
+			entries = append(entries, journalEntry{Op: opArchive, At: now, Task: cloneTask(t)})
+		}
+		if err := s.writeArchiveUnsafe(year, merged); err != nil {
+			return 0, err
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Task.ID < entries[j].Task.ID })
+	if err := s.commitUnsafe(entries...); err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// ArchiveYears returns the years with an archive file, newest first.
+func (s *Store) ArchiveYears() ([]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.archiveYearsUnsafe()
+}
+
+func (s *Store) archiveYearsUnsafe() ([]int, error) {
+	entries, err := os.ReadDir(s.archiveDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read archive: %w", err)
+	}
+	var years []int
+	for _, e := range entries {
+		if m := archiveFilePattern.FindStringSubmatch(e.Name()); m != nil {
+			year, _ := strconv.Atoi(m[1])
+			years = append(years, year)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(years)))
+	return years, nil
+}
+
+// Archived returns the archived tasks of a year, sorted by day and ID.
+func (s *Store) Archived(year int) ([]*Task, error) {
+	s.mu.Lock() // reading fills the cache
+	defer s.mu.Unlock()
+
+	tasks, err := s.readArchiveUnsafe(year)
+	if err != nil {
+		return nil, err
+	}
+	return s.cloneArchivedUnsafe(tasks, func(*Task) bool { return true }), nil
+}
+
+// ArchivedByDate returns the archived tasks scheduled for the given day.
+func (s *Store) ArchivedByDate(date time.Time) ([]*Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(s.archivePath(date.Year())); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	tasks, err := s.readArchiveUnsafe(date.Year())
+	if err != nil {
+		return nil, err
+	}
+	return s.cloneArchivedUnsafe(tasks, func(t *Task) bool {
+		return t.Date.Month() == date.Month() && t.Date.Day() == date.Day()
+	}), nil
+}
+
+// cloneArchivedUnsafe copies the archived tasks that match keep. A task
+// that is back in the store, after an Unarchive cut short, is left out.
+func (s *Store) cloneArchivedUnsafe(tasks []*Task, keep func(*Task) bool) []*Task {
+	var out []*Task
+	for _, t := range tasks {
+		if keep(t) && s.findUnsafe(t.ID) == nil {
+			out = append(out, cloneTask(t))
+		}
+	}
+	return out
+}
+
+// Unarchive moves a task from its archive file back into the store.
+func (s *Store) Unarchive(id int) (*Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.beginUnsafe(); err != nil {
+		return nil, err
+	}
+	defer s.unlockUnsafe()
+
+	years, err := s.archiveYearsUnsafe()
+	if err != nil {
+		return nil, err
+	}
+	for _, year := range years {
+		tasks, err := s.readArchiveUnsafe(year)
+		if err != nil {
+			return nil, err
+		}
+		for _, cur := range tasks {
+			if cur.ID != id {
+				continue
+			}
+			t := cloneTask(cur)
+			t.UpdatedAt = time.Now()
+			t.Revision++
+			// The store first: a crash before the archive is rewritten
+			// leaves a copy there that is ignored.
+			if err := s.commitUnsafe(journalEntry{Op: opUnarchive, At: t.UpdatedAt, Task: t}); err != nil {
+				return nil, err
+			}
+			rest := removeTask(append([]*Task(nil), tasks...), id)
+			if err := s.writeArchiveUnsafe(year, rest); err != nil {
+				return nil, err
+			}
+			return cloneTask(t), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// readArchiveUnsafe returns the tasks of a year's archive file, reading it
+// on first use. A missing file is an empty archive.
+func (s *Store) readArchiveUnsafe(year int) ([]*Task, error) {
+	if tasks, ok := s.archive[year]; ok {
+		return tasks, nil
+	}
+	data, err := os.ReadFile(s.archivePath(year))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	if data, err = unseal(s.opts.secret, data); err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	snap, err := decodeSnapshot(data)
+	if err != nil {
+		return nil, fmt.Errorf("decode archive %d: %w", year, err)
+	}
+	if s.archive == nil {
+		s.archive = map[int][]*Task{}
+	}
+	s.archive[year] = snap.Tasks
+	return snap.Tasks, nil
+}
+
+// writeArchiveUnsafe replaces a year's archive file with tasks, or removes
+// it when there are none left.
+func (s *Store) writeArchiveUnsafe(year int, tasks []*Task) error {
+	path := s.archivePath(year)
+	if len(tasks) == 0 {
+		delete(s.archive, year)
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove archive: %w", err)
+		}
+		return nil
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return a.ID < b.ID
+	})
+	data, err := json.MarshalIndent(snapshot{Version: CurrentVersion, Tasks: tasks}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode archive: %w", err)
+	}
+	if data, err = sealWith(s.opts.secret, data); err != nil {
+		return fmt.Errorf("encrypt archive: %w", err)
+	}
+	if err := writeFileAtomic(path, append(data, '\n')); err != nil {
+		return err
+	}
+	if s.archive == nil {
+		s.archive = map[int][]*Task{}
+	}
+	s.archive[year] = tasks
+	return nil
+}
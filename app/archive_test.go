@@ -0,0 +1,105 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	day2023 := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	day2024 := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	open, _ := s.Add("still open", "", nil, day2023)
+	for _, day := range []time.Time{day2023, day2024} {
+		task, _ := s.Add("done", "", nil, day)
+		s.MarkCompleted(task.ID, true)
+	}
+
+	n, err := s.Archive(time.Now().Add(time.Hour))
+	if err != nil || n != 2 {
+		t.Fatalf("Archive() = %d, %v, want 2", n, err)
+	}
+	if tasks := s.List(); len(tasks) != 1 || tasks[0].ID != open.ID {
+		t.Errorf("List() after Archive = %v, want only the open task", tasks)
+	}
+	if years, err := s.ArchiveYears(); err != nil || !reflect.DeepEqual(years, []int{2024, 2023}) {
+		t.Errorf("ArchiveYears() = %v, %v, want [2024 2023]", years, err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(path), "archive", "tasks", "archive-2024.json")); err != nil {
+		t.Errorf("archive file: %v", err)
+	}
+	if got, err := s.ArchivedByDate(day2024); err != nil || len(got) != 1 || got[0].ID != 3 {
+		t.Errorf("ArchivedByDate() = %v, %v, want task 3", got, err)
+	}
+
+	// Archived IDs are not handed out again, not even after reopening.
+	if task, _ := s.Add("new", "", nil, day2024); task.ID != 4 {
+		t.Errorf("Add() after Archive got ID %d, want 4", task.ID)
+	}
+	s.Delete(4)
+	s.Purge(4)
+	s.Close()
+	s, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer s.Close()
+	if task, _ := s.Add("newer", "", nil, day2024); task.ID != 5 {
+		t.Errorf("Add() after reopening got ID %d, want 5", task.ID)
+	}
+
+	task, err := s.Unarchive(3)
+	if err != nil || task.ID != 3 || !task.IsCompleted() {
+		t.Fatalf("Unarchive() = %v, %v", task, err)
+	}
+	if _, err := s.Get(3); err != nil {
+		t.Errorf("Get() after Unarchive error = %v", err)
+	}
+	if years, _ := s.ArchiveYears(); !reflect.DeepEqual(years, []int{2023}) {
+		t.Errorf("ArchiveYears() after Unarchive = %v, want [2023]", years)
+	}
+	if _, err := s.Unarchive(3); err != ErrNotFound {
+		t.Errorf("Unarchive() twice error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestArchiveAfter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	old, _ := s.Add("old", "", nil, time.Now().AddDate(-2, 0, 0))
+	s.MarkCompleted(old.ID, true)
+	s.Close()
+
+	// Completed just now, so not old enough yet.
+	s, err = Load(path, WithArchiveAfter(24*time.Hour))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := len(s.List()); got != 1 {
+		t.Errorf("len(List()) = %d, want 1", got)
+	}
+	s.Close()
+
+	s, err = Load(path, WithArchiveAfter(time.Nanosecond))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer s.Close()
+	if got := len(s.List()); got != 0 {
+		t.Errorf("len(List()) = %d, want 0", got)
+	}
+	if got, _ := s.Archived(old.Date.Year()); len(got) != 1 {
+		t.Errorf("Archived() = %v, want the old task", got)
+	}
+}
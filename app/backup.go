@@ -0,0 +1,229 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backup defaults, used when the "backup.*" config keys are not set.
+const (
+	DefaultBackupEvery = 50
+	DefaultBackupKeep  = 10
+)
+
+// backupIDLayout names backups by creation time; it sorts chronologically.
+const backupIDLayout = "20060102-150405.000"
+
+// Backups manages timestamped copies of a task store in a directory.
+// Backups are plain JSON snapshots, whatever the backend of the store.
+type Backups struct {
+	Dir    string
+	Prefix string
+	Keep   int     // newest backups to keep; zero or less keeps all
+	Secret *Secret // encrypts new backups and opens encrypted ones
+}
+
+// Backup describes one snapshot in a Backups directory.
+type Backup struct {
+	ID    string
+	Path  string
+	Time  time.Time
+	Tasks int
+}
+
+// NewBackups returns the backup set for the store at path. An empty dir
+// means a "backups" directory next to the store.
+func NewBackups(path, dir string, keep int) Backups {
+	if dir == "" {
+		dir = filepath.Join(filepath.Dir(path), "backups")
+	}
+	base := filepath.Base(path)
+	return Backups{
+		Dir:    dir,
+		Prefix: strings.TrimSuffix(base, filepath.Ext(base)),
+		Keep:   keep,
+	}
+}
+
+// Create writes tasks as a new backup and prunes old ones.
+func (b Backups) Create(tasks []*Task) (Backup, error) {
+	if err := os.MkdirAll(b.Dir, 0o755); err != nil {
+		return Backup{}, fmt.Errorf("mkdir backup dir: %w", err)
+	}
+	data, err := json.MarshalIndent(snapshot{Version: CurrentVersion, Tasks: tasks}, "", "  ")
+	if err != nil {
+		return Backup{}, fmt.Errorf("encode backup: %w", err)
+	}
+	if data, err = sealWith(b.Secret, data); err != nil {
+		return Backup{}, fmt.Errorf("encrypt backup: %w", err)
+	}
+
+	// Never overwrite a backup taken in the same millisecond; move on to
+	// the next free ID instead.
+	now := time.Now()
+	var (
+		id, path string
+		f        *os.File
+	)
+	for {
+		id = now.Format(backupIDLayout)
+		path = b.path(id)
+		f, err = os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if !errors.Is(err, os.ErrExist) {
+			break
+		}
+		now = now.Add(time.Millisecond)
+	}
+	if err != nil {
+		return Backup{}, fmt.Errorf("create backup: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		os.Remove(path)
+		return Backup{}, fmt.Errorf("write backup: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return Backup{}, fmt.Errorf("close backup: %w", err)
+	}
+
+	if err := b.prune(); err != nil {
+		return Backup{}, err
+	}
+	return Backup{ID: id, Path: path, Time: now, Tasks: len(tasks)}, nil
+}
+
+// List returns all backups, oldest first.
+func (b Backups) List() ([]Backup, error) {
+	ids, err := b.ids()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Backup, 0, len(ids))
+	for _, id := range ids {
+		tasks, err := b.Read(id)
+		if err != nil {
+			return nil, err
+		}
+		at, _ := time.ParseInLocation(backupIDLayout, id, time.Local)
+		out = append(out, Backup{ID: id, Path: b.path(id), Time: at, Tasks: len(tasks)})
+	}
+	return out, nil
+}
+
+// Read returns the tasks stored in the backup with the given ID.
+func (b Backups) Read(id string) ([]*Task, error) {
+	data, err := os.ReadFile(b.path(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("backup %q not found", id)
+		}
+		return nil, fmt.Errorf("read backup: %w", err)
+	}
+	if data, err = unseal(b.Secret, data); err != nil {
+		return nil, fmt.Errorf("open backup %s: %w", id, err)
+	}
+	snap, err := decodeSnapshot(data)
+	if err != nil {
+		return nil, fmt.Errorf("decode backup %s: %w", id, err)
+	}
+	return snap.Tasks, nil
+}
+
+// Rekey rewrites every backup encrypted with secret, or in plain JSON when
+// secret is nil. Backups are read with b.Secret.
+func (b Backups) Rekey(secret *Secret) error {
+	ids, err := b.ids()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		tasks, err := b.Read(id)
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(snapshot{Version: CurrentVersion, Tasks: tasks}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode backup: %w", err)
+		}
+		if data, err = sealWith(secret, data); err != nil {
+			return fmt.Errorf("encrypt backup: %w", err)
+		}
+		if err := writeFileAtomic(b.path(id), append(data, '\n')); err != nil {
+			return fmt.Errorf("rewrite backup %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func (b Backups) path(id string) string {
+	return filepath.Join(b.Dir, b.Prefix+"-"+id+".json")
+}
+
+// ids returns the IDs of all backups in the directory, oldest first.
+func (b Backups) ids() ([]string, error) {
+	entries, err := os.ReadDir(b.Dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read backup dir: %w", err)
+	}
+	var ids []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, b.Prefix+"-") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(name, b.Prefix+"-"), ".json")
+		if _, err := time.Parse(backupIDLayout, id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// prune removes the oldest backups beyond Keep.
+func (b Backups) prune() error {
+	if b.Keep <= 0 {
+		return nil
+	}
+	ids, err := b.ids()
+	if err != nil {
+		return err
+	}
+	for len(ids) > b.Keep {
+		if err := os.Remove(b.path(ids[0])); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("prune backup: %w", err)
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
+
+// autoBackup decides when a store takes automatic backups: before its first
+// save in this session, then before every Nth save.
+type autoBackup struct {
+	mu      sync.Mutex
+	backups Backups
+	every   int
+	saves   int
+}
+
+// due counts a save and reports whether a backup should be taken before it.
+func (a *autoBackup) due() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	due := a.saves == 0 || (a.every > 0 && a.saves%a.every == 0)
+	a.saves++
+	return due
+}
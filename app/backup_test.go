@@ -0,0 +1,56 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAutomaticBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.json")
+	backupDir := filepath.Join(dir, "backups")
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	s, _ := Load(path)
+	for _, title := range []string{"a", "b", "c"} {
+		s.Add(title, "", nil, day)
+	}
+	s.Close()
+
+	// New session: back up before the first save, then before every 2nd.
+	s, _ = Load(path, WithBackups(backupDir, 2, 2))
+	defer s.Close()
+	for _, title := range []string{"d", "e", "f", "g", "h"} {
+		if _, err := s.Add(title, "", nil, day); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	backups, err := NewBackups(path, backupDir, 0).List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	// Backups were due before saves 1, 3 and 5; only the newest 2 are kept.
+	if len(backups) != 2 {
+		t.Fatalf("len(List()) = %d, want 2", len(backups))
+	}
+	if backups[0].Tasks != 5 || backups[1].Tasks != 7 {
+		t.Errorf("backup task counts = %d, %d, want 5, 7", backups[0].Tasks, backups[1].Tasks)
+	}
+
+	tasks, err := NewBackups(path, backupDir, 0).Read(backups[0].ID)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if err := s.ReplaceAll(tasks); err != nil {
+		t.Fatalf("ReplaceAll() error = %v", err)
+	}
+	if got := len(s.List()); got != 5 {
+		t.Errorf("len(List()) after restore = %d, want 5", got)
+	}
+	// The IDs of f, g and h, dropped by the restore, are not handed out again.
+	if s.NextID != 9 {
+		t.Errorf("NextID after restore = %d, want 9", s.NextID)
+	}
+}
@@ -0,0 +1,141 @@
+package app
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TaskChange is an entry of a task's history: a field that an update,
+// completion or deletion changed, with its old and new value as shown to
+// the user.
+type TaskChange struct {
+	At    time.Time `json:"at"`
+	Field string    `json:"field"` // a merged field, e.g. "title" or "due"
+	Old   string    `json:"old"`
+	New   string    `json:"new"`
+}
+
+func (c TaskChange) String() string {
+	return fmt.Sprintf("%s %s: %s → %s", c.At.Format("2006-01-02 15:04"), c.Field, c.Old, c.New)
+}
+
+// recordChanges appends an entry to the history of t for every recorded
+// field that differs from before. Both are copies of the same task, so
+// the history is copied before it grows rather than appended to in place.
+func recordChanges(before, t *Task, at time.Time) {
+	for _, f := range taskFields {
+		if !f.recorded {
+			continue
+		}
+		old, cur := f.get(before), f.get(t)
+		if f.equal(old, cur) || f.show(old) == f.show(cur) {
+			continue
+		}
+		t.Changes = append(t.Changes[:len(t.Changes):len(t.Changes)], TaskChange{
+			At:    at,
+			Field: f.name,
+			Old:   f.show(old),
+			New:   f.show(cur),
+		})
+	}
+}
+
+// mergeChanges merges two histories of a task into one, by time. Entries
+// both have are kept once.
+func mergeChanges(ours, theirs []TaskChange) []TaskChange {
+	out := make([]TaskChange, 0, len(ours)+len(theirs))
+	i, j := 0, 0
+	for i < len(ours) || j < len(theirs) {
+		switch {
+		case j == len(theirs) || (i < len(ours) && ours[i].At.Before(theirs[j].At)):
+			out = append(out, ours[i])
+			i++
+		case i == len(ours) || theirs[j].At.Before(ours[i].At):
+			out = append(out, theirs[j])
+			j++
+		default:
+			out = append(out, ours[i])
+			if !sameChange(ours[i], theirs[j]) {
+				out = append(out, theirs[j])
+			}
+			i++
+			j++
+		}
+	}
+	return out
+}
+
+func sameChange(a, b TaskChange) bool {
+	return a.At.Equal(b.At) && a.Field == b.Field && a.Old == b.Old && a.New == b.New
+}
+
+func equalChanges(a, b any) bool {
+	x, y := a.([]TaskChange), b.([]TaskChange)
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if !sameChange(x[i], y[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// formatChanges and parseChanges keep a task's history in a column of
+// the SQLite store, as JSON; NULL when there is none.
+func formatChanges(changes []TaskChange) (any, error) {
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return nil, fmt.Errorf("encode history: %w", err)
+	}
+	return string(data), nil
+}
+
+func parseChanges(s sql.NullString) ([]TaskChange, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	var changes []TaskChange
+	if err := json.Unmarshal([]byte(s.String), &changes); err != nil {
+		return nil, fmt.Errorf("decode history: %w", err)
+	}
+	return changes, nil
+}
+
+// formatNoteChange and parseNoteChange keep an entry of a task's history
+// in a "history::" property of a daily note:
+//
+//	history:: 2024-05-02T09:13:00+02:00 title "Draft" → "Write report"
+func formatNoteChange(c TaskChange) string {
+	return fmt.Sprintf("%s %s %s → %s", c.At.Format(markdownTimeFormat), c.Field, c.Old, c.New)
+}
+
+func parseNoteChange(s string) (TaskChange, error) {
+	at, rest, _ := strings.Cut(s, " ")
+	field, rest, _ := strings.Cut(rest, " ")
+	t, err := parseNoteTime(at)
+	if err != nil {
+		return TaskChange{}, err
+	}
+	c := TaskChange{At: t, Field: field}
+	// Quoted values may hold an arrow of their own.
+	var ok bool
+	if q, err := strconv.QuotedPrefix(rest); err == nil {
+		c.Old = q
+		c.New, ok = strings.CutPrefix(rest[len(q):], " → ")
+	} else {
+		c.Old, c.New, ok = strings.Cut(rest, " → ")
+	}
+	if !ok || field == "" {
+		return TaskChange{}, fmt.Errorf("invalid history entry %q", s)
+	}
+	return c, nil
+}
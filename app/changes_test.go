@@ -0,0 +1,22 @@
+package app
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMergeChanges(t *testing.T) {
+	at := func(h int) time.Time { return time.Date(2024, 5, 1, h, 0, 0, 0, time.UTC) }
+	shared := TaskChange{At: at(1), Field: "title", Old: `"a"`, New: `"b"`}
+	ours := []TaskChange{shared, {At: at(3), Field: "due", Old: "none", New: "2024-05-02 10:00"}}
+	theirs := []TaskChange{shared, {At: at(2), Field: "notes", Old: `""`, New: `"x"`}}
+
+	var fields []string
+	for _, c := range mergeChanges(ours, theirs) {
+		fields = append(fields, c.Field)
+	}
+	if got, want := fmt.Sprint(fields), "[title notes due]"; got != want {
+		t.Errorf("mergeChanges() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,38 @@
+package app_test
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+
+	"taskman/app"
+	"taskman/app/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	for _, backend := range []string{app.BackendJSON, app.BackendSQLite, app.BackendMarkdown} {
+		t.Run(backend, func(t *testing.T) {
+			storetest.Run(t, func(t *testing.T) app.TaskStore {
+				path := filepath.Join(t.TempDir(), "tasks."+backend)
+				store, err := app.Open(backend, path)
+				if err != nil {
+					t.Fatalf("Open() error = %v", err)
+				}
+				t.Cleanup(func() { store.(io.Closer).Close() })
+				return store
+			})
+			storetest.RunSaved(t, func(t *testing.T, dir string) app.TaskStore {
+				store, err := app.Open(backend, filepath.Join(dir, "tasks."+backend))
+				if err != nil {
+					t.Fatalf("Open() error = %v", err)
+				}
+				return store
+			})
+		})
+	}
+	t.Run("memory", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) app.TaskStore {
+			return app.NewMemoryStore()
+		})
+	})
+}
@@ -0,0 +1,219 @@
+package app
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// An encrypted store wraps every file it writes, and every journal line,
+// in a sealed envelope: AES-256-GCM under a key derived with scrypt from a
+// passphrase or the contents of a key file. Each envelope carries its own
+// salt and nonce, so envelopes written by different processes can be mixed.
+const sealedFormat = "aes-256-gcm+scrypt/v1"
+
+// scrypt parameters of sealedFormat. Changing them needs a new format name.
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+	keyLen  = 32
+	saltLen = 16
+)
+
+// Errors returned when opening an encrypted store.
+var (
+	ErrEncrypted = errors.New("task store is encrypted; a passphrase or key file is required")
+	ErrWrongKey  = errors.New("wrong passphrase or key file")
+)
+
+// sealed is the envelope of an encrypted file or journal line.
+type sealed struct {
+	Format string `json:"encrypted"`
+	Salt   []byte `json:"salt"`
+	Nonce  []byte `json:"nonce"`
+	Data   []byte `json:"data"`
+}
+
+// Secret holds what a store is encrypted with. Derived keys are cached,
+// so only the first use of every salt pays for scrypt.
+type Secret struct {
+	secret []byte
+
+	mu   sync.Mutex
+	salt []byte // salt of everything this process seals
+	keys map[string]cipher.AEAD
+}
+
+// NewSecret returns a Secret for a passphrase.
+func NewSecret(passphrase []byte) *Secret {
+	return &Secret{secret: bytes.Clone(passphrase), keys: map[string]cipher.AEAD{}}
+}
+
+// ReadKeyFile returns a Secret for the contents of a key file.
+func ReadKeyFile(path string) (*Secret, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+	data = bytes.TrimRight(data, "\r\n")
+	if len(data) == 0 {
+		return nil, fmt.Errorf("key file %s is empty", path)
+	}
+	return NewSecret(data), nil
+}
+
+// CreateKeyFile writes a new random key to path, which must not exist yet,
+// and returns its Secret.
+func CreateKeyFile(path string) (*Secret, error) {
+	key := make([]byte, keyLen)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generate key: %w", err)
+	}
+	data := []byte(fmt.Sprintf("%x\n", key))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("create key file: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("write key file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("write key file: %w", err)
+	}
+	return NewSecret(bytes.TrimRight(data, "\n")), nil
+}
+
+// IsEncrypted reports whether the JSON store at path is encrypted. A store
+// that does not exist yet is not.
+func IsEncrypted(path string) (bool, error) {
+	for _, p := range []string{path, path + ".journal"} {
+		f, err := os.Open(p)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		head := make([]byte, 64)
+		n, err := io.ReadFull(f, head)
+		f.Close()
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+			return false, err
+		}
+		if n > 0 {
+			return isSealed(head[:n]), nil
+		}
+	}
+	return false, nil
+}
+
+// isSealed reports whether data starts like a sealed envelope.
+func isSealed(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte(`{"encrypted":`))
+}
+
+// seal encrypts plain into a single-line envelope.
+func (s *Secret) seal(plain []byte) ([]byte, error) {
+	s.mu.Lock()
+	if s.salt == nil {
+		salt := make([]byte, saltLen)
+		if _, err := rand.Read(salt); err != nil {
+			s.mu.Unlock()
+			return nil, fmt.Errorf("generate salt: %w", err)
+		}
+		s.salt = salt
+	}
+	salt := s.salt
+	s.mu.Unlock()
+
+	aead, err := s.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return json.Marshal(sealed{
+		Format: sealedFormat,
+		Salt:   salt,
+		Nonce:  nonce,
+		Data:   aead.Seal(nil, nonce, plain, nil),
+	})
+}
+
+// open decrypts an envelope written by seal.
+func (s *Secret) open(data []byte) ([]byte, error) {
+	var env sealed
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+	if env.Format != sealedFormat {
+		return nil, fmt.Errorf("unsupported encryption %q", env.Format)
+	}
+	aead, err := s.aead(env.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Nonce) != aead.NonceSize() {
+		return nil, ErrWrongKey
+	}
+	plain, err := aead.Open(nil, env.Nonce, env.Data, nil)
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	return plain, nil
+}
+
+func (s *Secret) aead(salt []byte) (cipher.AEAD, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if aead, ok := s.keys[string(salt)]; ok {
+		return aead, nil
+	}
+	key, err := scrypt.Key(s.secret, salt, scryptN, scryptR, scryptP, keyLen)
+	if err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	s.keys[string(salt)] = aead
+	return aead, nil
+}
+
+// sealWith seals data when secret is set and returns it unchanged otherwise.
+func sealWith(secret *Secret, data []byte) ([]byte, error) {
+	if secret == nil {
+		return data, nil
+	}
+	return secret.seal(data)
+}
+
+// unseal opens data if it is sealed and returns it unchanged otherwise.
+func unseal(secret *Secret, data []byte) ([]byte, error) {
+	if !isSealed(data) {
+		return data, nil
+	}
+	if secret == nil {
+		return nil, ErrEncrypted
+	}
+	return secret.open(bytes.TrimSpace(data))
+}
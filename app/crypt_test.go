@@ -0,0 +1,102 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEncryptedStore(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.json")
+	secret := NewSecret([]byte("correct horse"))
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	s, err := Load(path, WithSecret(secret), WithBackups("", 1, 0))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	s.Add("call ACME about the invoice", "", nil, day)
+	s.Add("second", "", nil, day)
+
+	journal, _ := os.ReadFile(path + ".journal")
+	if bytes.Contains(journal, []byte("ACME")) {
+		t.Errorf("journal contains plain text: %s", journal)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	snapshot, _ := os.ReadFile(path)
+	if bytes.Contains(snapshot, []byte("ACME")) {
+		t.Errorf("snapshot contains plain text: %s", snapshot)
+	}
+	if ok, err := IsEncrypted(path); !ok || err != nil {
+		t.Errorf("IsEncrypted() = %v, %v, want true", ok, err)
+	}
+
+	if _, err := Load(path); !errors.Is(err, ErrEncrypted) {
+		t.Errorf("Load() without secret error = %v, want ErrEncrypted", err)
+	}
+	if _, err := Load(path, WithSecret(NewSecret([]byte("wrong")))); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("Load() with wrong secret error = %v, want ErrWrongKey", err)
+	}
+
+	s, err = Load(path, WithSecret(secret))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := len(s.List()); got != 2 {
+		t.Fatalf("len(List()) = %d, want 2", got)
+	}
+
+	// The backup taken before the second task was added is encrypted too.
+	backups := NewBackups(path, "", 0)
+	backups.Secret = secret
+	list, err := backups.List()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("List() = %v, %v, want 1 backup", list, err)
+	}
+	backups.Secret = nil
+	if _, err := backups.Read(list[0].ID); !errors.Is(err, ErrEncrypted) {
+		t.Errorf("Read() backup without secret error = %v, want ErrEncrypted", err)
+	}
+
+	if err := s.Rekey(nil); err != nil {
+		t.Fatalf("Rekey(nil) error = %v", err)
+	}
+	s.Close()
+	plain, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() after decrypting error = %v", err)
+	}
+	if got := len(plain.List()); got != 2 {
+		t.Errorf("len(List()) after decrypting = %d, want 2", got)
+	}
+}
+
+func TestKeyFile(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "taskman.key")
+	created, err := CreateKeyFile(keyPath)
+	if err != nil {
+		t.Fatalf("CreateKeyFile() error = %v", err)
+	}
+	if _, err := CreateKeyFile(keyPath); err == nil {
+		t.Errorf("CreateKeyFile() over an existing file succeeded")
+	}
+	read, err := ReadKeyFile(keyPath)
+	if err != nil {
+		t.Fatalf("ReadKeyFile() error = %v", err)
+	}
+
+	sealed, err := created.seal([]byte("hello"))
+	if err != nil {
+		t.Fatalf("seal() error = %v", err)
+	}
+	plain, err := read.open(sealed)
+	if err != nil || string(plain) != "hello" {
+		t.Errorf("open() = %q, %v, want hello", plain, err)
+	}
+}
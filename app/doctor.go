@@ -0,0 +1,193 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The doctor checks the tasks of a store against DoctorRules. Stores load
+// whatever their files hold, and hand edits or sync conflicts can leave
+// tasks behind that the store would never have written itself.
+
+// Finding is a task that breaks a doctor rule.
+type Finding struct {
+	Rule    string
+	ID      int
+	Title   string
+	Problem string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: #%d %q: %s", f.Rule, f.ID, f.Title, f.Problem)
+}
+
+// DoctorRule is a named check of a task, with the repair that makes the
+// task pass it.
+type DoctorRule struct {
+	Name        string
+	Description string
+
+	check func(d *doctor, t *Task) string // the problem with t, if any
+	fix   func(d *doctor, t *Task)
+}
+
+// doctor is the state of one run over a list of tasks.
+type doctor struct {
+	seen   map[int]*Task // tasks by ID, as far as the run has got
+	uuids  map[string]*Task
+	nextID int
+	now    time.Time
+}
+
+// DoctorRules are the rules the doctor checks, in the order it checks them.
+var DoctorRules = []DoctorRule{
+	{
+		Name:        "duplicate-id",
+		Description: "no two tasks share an ID, in the trash and archive too; later ones get a new ID",
+		check: func(d *doctor, t *Task) string {
+			if other, ok := d.seen[t.ID]; ok {
+				return fmt.Sprintf("ID is also used by %q", other.Title)
+			}
+			return ""
+		},
+		fix: func(d *doctor, t *Task) {
+			t.ID = d.nextID
+			d.nextID++
+		},
+	},
+	{
+		Name:        "duplicate-uuid",
+		Description: "no two tasks share a UUID, in the trash and archive too; later ones get a new UUID",
+		check: func(d *doctor, t *Task) string {
+			if other, ok := d.uuids[t.UUID]; ok {
+				return fmt.Sprintf("UUID is also used by %q", other.Title)
+			}
+			return ""
+		},
+		fix: func(d *doctor, t *Task) {
+			t.UUID = newUUID()
+		},
+	},
+	{
+		Name:        "empty-title",
+		Description: "every task has a title; an empty one is taken from the notes",
+		check: func(d *doctor, t *Task) string {
+			if strings.TrimSpace(t.Title) == "" {
+				return "title is empty"
+			}
+			return ""
+		},
+		fix: func(d *doctor, t *Task) {
+			t.Title, _, _ = strings.Cut(strings.TrimSpace(t.Notes), "\n")
+			if t.Title == "" {
+				t.Title = "Untitled task"
+			}
+		},
+	},
+	{
+		Name:        "zero-date",
+		Description: "every task is scheduled for a day; a missing one is the day it was created",
+		check: func(d *doctor, t *Task) string {
+			if t.Date.IsZero() {
+				return "day is not set"
+			}
+			return ""
+		},
+		fix: func(d *doctor, t *Task) {
+			t.Date = d.now
+			for _, at := range []*time.Time{&t.CreatedAt, t.CompletedAt, &t.UpdatedAt} {
+				if at != nil && !at.IsZero() {
+					t.Date = *at
+					break
+				}
+			}
+		},
+	},
+	{
+		Name:        "completed-before-created",
+		Description: "no task is completed before it was created; such a task was created when completed",
+		check: func(d *doctor, t *Task) string {
+			if t.CompletedAt != nil && t.CompletedAt.Before(t.CreatedAt) {
+				return fmt.Sprintf("completed %s but created %s",
+					t.CompletedAt.Format(time.DateTime), t.CreatedAt.Format(time.DateTime))
+			}
+			return ""
+		},
+		fix: func(d *doctor, t *Task) {
+			t.CreatedAt = *t.CompletedAt
+		},
+	},
+}
+
+// FindDoctorRule returns the rule called name.
+func FindDoctorRule(name string) (DoctorRule, bool) {
+	for _, r := range DoctorRules {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return DoctorRule{}, false
+}
+
+// newDoctor returns a doctor that has already seen parked, the tasks in
+// the trash or archive. They are not checked themselves, but a task that
+// shares an ID or UUID with one of them clashes with it once it is
+// restored or unarchived.
+func newDoctor(parked []*Task, nextID int) *doctor {
+	d := &doctor{seen: map[int]*Task{}, uuids: map[string]*Task{}, nextID: nextID, now: time.Now()}
+	for _, t := range parked {
+		d.seen[t.ID], d.uuids[t.UUID] = t, t
+	}
+	return d
+}
+
+// Diagnose checks tasks against rules and returns what breaks them, in
+// the order of tasks. parked are the tasks in the trash or archive, see
+// KnownTasks.
+func Diagnose(tasks, parked []*Task, rules []DoctorRule) []Finding {
+	d := newDoctor(parked, 0)
+	var findings []Finding
+	for _, t := range tasks {
+		for _, r := range rules {
+			if problem := r.check(d, t); problem != "" {
+				findings = append(findings, Finding{Rule: r.Name, ID: t.ID, Title: t.Title, Problem: problem})
+			}
+		}
+		if _, ok := d.seen[t.ID]; !ok {
+			d.seen[t.ID] = t
+		}
+		if _, ok := d.uuids[t.UUID]; !ok {
+			d.uuids[t.UUID] = t
+		}
+	}
+	return findings
+}
+
+// Repair returns a copy of tasks that passes rules, and what it fixed;
+// parked are left as they are. A task given a new ID gets nextID, then the
+// IDs after it, so nextID must be free along with every ID above it.
+// Repaired tasks count as updated.
+func Repair(tasks, parked []*Task, nextID int, rules []DoctorRule) ([]*Task, []Finding) {
+	d := newDoctor(parked, nextID)
+	out := make([]*Task, 0, len(tasks))
+	var findings []Finding
+	for _, t := range tasks {
+		t = cloneTask(t)
+		fixed := false
+		for _, r := range rules {
+			if problem := r.check(d, t); problem != "" {
+				findings = append(findings, Finding{Rule: r.Name, ID: t.ID, Title: t.Title, Problem: problem})
+				r.fix(d, t)
+				fixed = true
+			}
+		}
+		if fixed {
+			t.UpdatedAt = d.now
+			t.Revision++
+		}
+		d.seen[t.ID], d.uuids[t.UUID] = t, t
+		out = append(out, t)
+	}
+	return out, findings
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// brokenStore is a hand-edited store file with a duplicate ID, an empty
+// title, a task without a day and one completed before it was created.
+const brokenStore = `{
+  "version": 2,
+  "tasks": [
+    {"id": 1, "date": "2024-05-01T00:00:00Z", "title": "write report", "created_at": "2024-05-01T09:00:00Z", "revision": 1},
+    {"id": 1, "date": "2024-05-01T00:00:00Z", "title": "call bob", "created_at": "2024-05-01T10:00:00Z", "revision": 1},
+    {"id": 2, "date": "2024-05-02T00:00:00Z", "title": " ", "notes": "buy milk\nand eggs", "created_at": "2024-05-02T09:00:00Z", "revision": 1},
+    {"id": 3, "title": "plan week", "created_at": "2024-05-03T09:00:00Z", "revision": 1},
+    {"id": 4, "date": "2024-05-04T00:00:00Z", "title": "pay rent", "created_at": "2024-05-04T09:00:00Z", "completed_at": "2024-05-03T09:00:00Z", "revision": 1}
+  ]
+}
+`
+
+func TestDoctor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	if err := os.WriteFile(path, []byte(brokenStore), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer s.Close()
+
+	tasks := s.List()
+	findings := Diagnose(tasks, nil, DoctorRules)
+	rules := map[string]int{}
+	for _, f := range findings {
+		rules[f.Rule] = f.ID
+	}
+	want := map[string]int{"duplicate-id": 1, "empty-title": 2, "zero-date": 3, "completed-before-created": 4}
+	if len(findings) != len(want) {
+		t.Fatalf("Diagnose() = %v, want one finding for each of %v", findings, want)
+	}
+	for rule, id := range want {
+		if rules[rule] != id {
+			t.Errorf("rule %s found task %d, want %d", rule, rules[rule], id)
+		}
+	}
+
+	rule, _ := FindDoctorRule("zero-date")
+	if got := Diagnose(tasks, nil, []DoctorRule{rule}); len(got) != 1 {
+		t.Errorf("Diagnose() with zero-date only = %v, want 1 finding", got)
+	}
+
+	repaired, fixed := Repair(tasks, nil, 10, DoctorRules)
+	if len(fixed) != len(findings) {
+		t.Errorf("Repair() fixed %v, want %v", fixed, findings)
+	}
+	if got := Diagnose(repaired, nil, DoctorRules); len(got) != 0 {
+		t.Errorf("Diagnose() after Repair() = %v, want none", got)
+	}
+	if tasks[0].Title == "" {
+		t.Errorf("Repair() changed its input")
+	}
+	if err := s.ReplaceAll(repaired); err != nil {
+		t.Fatalf("ReplaceAll() error = %v", err)
+	}
+
+	byTitle := map[string]*Task{}
+	for _, task := range s.List() {
+		byTitle[task.Title] = task
+	}
+	if got := byTitle["call bob"]; got == nil || got.ID != 10 || got.Revision != 2 {
+		t.Errorf("duplicate = %+v, want ID 10 at revision 2", got)
+	}
+	if byTitle["buy milk"] == nil {
+		t.Errorf("task with an empty title not named after its notes: %v", s.List())
+	}
+	if got := byTitle["plan week"]; got == nil || !got.Date.Equal(got.CreatedAt) {
+		t.Errorf("task without a day = %+v, want it on the day it was created", got)
+	}
+	day := time.Date(2024, 5, 3, 9, 0, 0, 0, time.UTC)
+	if got := byTitle["pay rent"]; got == nil || !got.CreatedAt.Equal(day) {
+		t.Errorf("task completed before created = %+v, want created %v", got, day)
+	}
+}
+
+// TestDoctorParked checks that a live task sharing an ID or UUID with one
+// in the trash or archive is found and repaired, and the parked one kept.
+func TestDoctorParked(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	task := func(id int, uuid, title string) *Task {
+		return &Task{ID: id, UUID: uuid, Title: title, Date: day, CreatedAt: day}
+	}
+	tasks := []*Task{task(1, "u1", "write report"), task(2, "u3", "call bob")}
+	parked := []*Task{task(1, "u2", "deleted"), task(3, "u3", "archived")}
+
+	findings := Diagnose(tasks, parked, DoctorRules)
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.Rule+" "+f.Title)
+	}
+	want := []string{"duplicate-id write report", "duplicate-uuid call bob"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("Diagnose() = %v, want %v", findings, want)
+	}
+
+	repaired, _ := Repair(tasks, parked, 4, DoctorRules)
+	if repaired[0].ID != 4 || repaired[1].UUID == "u3" {
+		t.Errorf("Repair() = %+v, %+v, want a new ID and a new UUID", repaired[0], repaired[1])
+	}
+	if parked[0].ID != 1 || parked[1].UUID != "u3" {
+		t.Errorf("Repair() changed the parked tasks")
+	}
+	if got := Diagnose(repaired, parked, DoctorRules); len(got) != 0 {
+		t.Errorf("Diagnose() after Repair() = %v, want none", got)
+	}
+}
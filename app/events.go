@@ -0,0 +1,159 @@
+package app
+
+import (
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Event is a change to a task, published by a store to its subscribers:
+// a TaskAdded, TaskUpdated, TaskCompleted or TaskDeleted.
+type Event interface {
+	// EventTask returns the task as the change left it.
+	EventTask() *Task
+}
+
+// TaskAdded is published when a task is added, or comes back from the
+// trash or the archive.
+type TaskAdded struct{ Task *Task }
+
+// TaskUpdated is published when a task is edited or replaced.
+type TaskUpdated struct{ Task *Task }
+
+// TaskCompleted is published when a task is completed or reopened;
+// Task.IsCompleted tells which.
+type TaskCompleted struct{ Task *Task }
+
+// TaskDeleted is published when a task leaves the task list, into the
+// trash or the archive.
+type TaskDeleted struct{ Task *Task }
+
+func (e TaskAdded) EventTask() *Task     { return e.Task }
+func (e TaskUpdated) EventTask() *Task   { return e.Task }
+func (e TaskCompleted) EventTask() *Task { return e.Task }
+func (e TaskDeleted) EventTask() *Task   { return e.Task }
+
+// Publisher is implemented by stores that publish an Event for every
+// change made through them. Changes made by other processes are not
+// published; see Watcher.
+type Publisher interface {
+	// Subscribe calls fn with every event, in order, once the change is
+	// saved, and returns a function that ends the subscription. fn runs
+	// with the store locked, so it must not block or use the store.
+	Subscribe(fn func(Event)) (cancel func())
+}
+
+// EventsMsg carries the events published since the last EventsMsg into
+// the Bubble Tea loop.
+type EventsMsg struct {
+	Events []Event
+}
+
+// EventQueue hands the events of a store to the Bubble Tea loop in order,
+// without ever blocking the store: Push is the subscriber, and the command
+// of Next delivers what has been pushed since as one EventsMsg.
+type EventQueue struct {
+	mu     sync.Mutex
+	events []Event
+	ready  chan struct{}
+}
+
+// NewEventQueue creates an empty queue.
+func NewEventQueue() *EventQueue {
+	return &EventQueue{ready: make(chan struct{}, 1)}
+}
+
+// Push queues e.
+func (q *EventQueue) Push(e Event) {
+	q.mu.Lock()
+	q.events = append(q.events, e)
+	q.mu.Unlock()
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Next returns a command that waits for events and delivers them. Run it
+// again after every EventsMsg to keep them coming.
+func (q *EventQueue) Next() tea.Cmd {
+	return func() tea.Msg {
+		<-q.ready
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		events := q.events
+		q.events = nil
+		return EventsMsg{Events: events}
+	}
+}
+
+var (
+	_ Publisher = (*Store)(nil)
+	_ Publisher = (*SQLiteStore)(nil)
+	_ Publisher = (*MemoryStore)(nil)
+	_ Publisher = (*MarkdownStore)(nil)
+)
+
+// subscribers keeps the subscriptions of a store. Stores embed it for
+// their Subscribe method.
+type subscribers struct {
+	mu   sync.Mutex
+	last int
+	subs []subscription
+}
+
+type subscription struct {
+	id int
+	fn func(Event)
+}
+
+func (s *subscribers) Subscribe(fn func(Event)) (cancel func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.last++
+	id := s.last
+	s.subs = append(s.subs, subscription{id: id, fn: fn})
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i, sub := range s.subs {
+			if sub.id == id {
+				s.subs = append(s.subs[:i:i], s.subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// publish hands the event of a journal op on t to every subscriber. Ops
+// that do not change the task list, like purging the trash, have none.
+func (s *subscribers) publish(op string, t *Task) {
+	if t == nil {
+		return
+	}
+	var e Event
+	switch t = cloneTask(t); op {
+	case opAdd, opRestore, opUnarchive:
+		e = TaskAdded{Task: t}
+	case opUpdate:
+		e = TaskUpdated{Task: t}
+	case opComplete:
+		e = TaskCompleted{Task: t}
+	case opDelete, opArchive:
+		e = TaskDeleted{Task: t}
+	default:
+		return
+	}
+	s.send(e)
+}
+
+// send hands e to every subscriber.
+func (s *subscribers) send(e Event) {
+	s.mu.Lock()
+	subs := s.subs
+	s.mu.Unlock()
+	for _, sub := range subs {
+		sub.fn(e)
+	}
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEvents(t *testing.T) {
+	for _, backend := range []string{BackendJSON, BackendSQLite, BackendMarkdown} {
+		t.Run(backend, func(t *testing.T) {
+			store, err := Open(backend, filepath.Join(t.TempDir(), "tasks"+fileExt(backend)))
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			t.Cleanup(func() { store.(io.Closer).Close() })
+
+			var got []string
+			cancel := store.(Publisher).Subscribe(func(e Event) {
+				got = append(got, fmt.Sprintf("%T %d", e, e.EventTask().ID))
+			})
+			day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+			title := "write the report"
+
+			a, _ := store.Add("write report", "", nil, day)
+			store.Update(a.ID, UpdateOptions{Title: &title})
+			store.ToggleCompleted(a.ID)
+			store.Delete(a.ID)
+			store.(Trasher).Restore(a.ID)
+			store.(Trasher).Purge(a.ID) // not in the trash
+			store.Update(a.ID, UpdateOptions{Title: new(string)})
+			cancel()
+			store.Add("unheard", "", nil, day)
+
+			want := []string{
+				"app.TaskAdded 1",
+				"app.TaskUpdated 1",
+				"app.TaskCompleted 1",
+				"app.TaskDeleted 1",
+				"app.TaskAdded 1",
+			}
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("events = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestTxEvents(t *testing.T) {
+	s, err := Load(filepath.Join(t.TempDir(), "tasks.json"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer s.Close()
+
+	q := NewEventQueue()
+	s.Subscribe(q.Push)
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	s.Tx(func(tx TaskStore) error {
+		tx.Add("dropped", "", nil, day)
+		return errors.New("stop")
+	})
+	s.Tx(func(tx TaskStore) error {
+		a, _ := tx.Add("kept", "", nil, day)
+		_, err := tx.MarkCompleted(a.ID, true)
+		return err
+	})
+
+	msg := q.Next()().(EventsMsg)
+	if len(msg.Events) != 2 {
+		t.Fatalf("events = %v, want the two of the committed Tx", msg.Events)
+	}
+	if e, ok := msg.Events[1].(TaskCompleted); !ok || !e.Task.IsCompleted() || e.Task.Title != "kept" {
+		t.Errorf("second event = %#v, want TaskCompleted of \"kept\"", msg.Events[1])
+	}
+}
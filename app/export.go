@@ -0,0 +1,141 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// An export is a plain JSON file in the format of the JSON store, holding
+// the live tasks of a store. Imports match tasks by UUID, not by ID, so
+// exports of different stores can be imported into one another.
+
+// Export writes tasks to w as an export.
+func Export(w io.Writer, tasks []*Task) error {
+	data, err := json.MarshalIndent(snapshot{Version: CurrentVersion, Tasks: tasks}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode export: %w", err)
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// ReadExport returns the tasks of an export, or of a plain JSON store file.
+// Tasks in the trash of a store file are left out.
+func ReadExport(data []byte) ([]*Task, error) {
+	if isSealed(data) {
+		return nil, fmt.Errorf("read export: %w", ErrEncrypted)
+	}
+	snap, err := decodeSnapshot(data)
+	if err != nil {
+		return nil, fmt.Errorf("decode export: %w", err)
+	}
+	return snap.Tasks, nil
+}
+
+// ImportReport describes what ImportTasks did.
+type ImportReport struct {
+	Added     int
+	Updated   int
+	Unchanged int
+	// Renumbered are added tasks whose ID was taken by another task.
+	Renumbered []Renumbering
+}
+
+// ImportTasks merges tasks into store by UUID, in one transaction if the
+// store supports them. A task the store does not know is added, under its
+// own ID if that is free and under a new one otherwise. A task it knows
+// replaces the store's copy, keeping the store's ID, if it was updated
+// later; tasks in the trash or the archive of store are left there.
+func ImportTasks(store TaskStore, tasks []*Task) (ImportReport, error) {
+	var report ImportReport
+	live, parked, err := KnownTasks(store)
+	if err != nil {
+		return report, err
+	}
+	byUUID := map[string]*Task{}
+	ids := map[int]bool{}
+	next := 1
+	for _, t := range append(live, parked...) {
+		byUUID[t.UUID] = t
+		ids[t.ID] = true
+		next = max(next, t.ID+1)
+	}
+	// New IDs also stay clear of those in the file, which are kept if free.
+	for _, t := range tasks {
+		next = max(next, t.ID+1)
+	}
+	isParked := map[string]bool{}
+	for _, t := range parked {
+		isParked[t.UUID] = true
+	}
+
+	err = Batch(store, func(tx TaskStore) error {
+		for _, t := range tasks {
+			t = cloneTask(t)
+			ensureUUID(t, nil)
+			cur, ok := byUUID[t.UUID]
+			switch {
+			case ok && (isParked[t.UUID] || !t.UpdatedAt.After(cur.UpdatedAt)):
+				report.Unchanged++
+				continue
+			case ok:
+				t.ID = cur.ID
+				report.Updated++
+			default:
+				if t.ID <= 0 || ids[t.ID] {
+					report.Renumbered = append(report.Renumbered, Renumbering{From: t.ID, To: next, Title: t.Title})
+					t.ID = next
+				}
+				ids[t.ID] = true
+				next = max(next, t.ID+1)
+				report.Added++
+			}
+			t.DeletedAt = nil
+			put, err := tx.Put(t)
+			if err != nil {
+				return fmt.Errorf("import task %s: %w", t.UUID, err)
+			}
+			byUUID[put.UUID] = put
+		}
+		return nil
+	})
+	return report, err
+}
+
+// FreeID returns an ID above those of every task of store, live, deleted
+// or archived.
+func FreeID(store TaskStore) (int, error) {
+	live, parked, err := KnownTasks(store)
+	if err != nil {
+		return 0, err
+	}
+	next := 1
+	for _, t := range append(live, parked...) {
+		next = max(next, t.ID+1)
+	}
+	return next, nil
+}
+
+// KnownTasks returns the live tasks of store, and those parked in its
+// trash or archive.
+func KnownTasks(store TaskStore) (live, parked []*Task, err error) {
+	live = store.List()
+	if trasher, ok := store.(Trasher); ok {
+		parked = append(parked, trasher.Trash()...)
+	}
+	if archive, ok := store.(Archiver); ok {
+		years, err := archive.ArchiveYears()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, year := range years {
+			archived, err := archive.Archived(year)
+			if err != nil {
+				return nil, nil, err
+			}
+			parked = append(parked, archived...)
+		}
+	}
+	return live, parked, nil
+}
@@ -0,0 +1,68 @@
+package app
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExportImport(t *testing.T) {
+	dir := t.TempDir()
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	src, err := Load(filepath.Join(dir, "tasks.json"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer src.Close()
+	dst, err := OpenSQLite(filepath.Join(dir, "tasks.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer dst.Close()
+
+	report, _ := src.Add("write report", "", nil, day)
+	src.Add("call bob", "", nil, day)
+	mine, _ := dst.Add("water plants", "", nil, day)
+
+	roundTrip := func() ImportReport {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := Export(&buf, src.List()); err != nil {
+			t.Fatalf("Export() error = %v", err)
+		}
+		tasks, err := ReadExport(buf.Bytes())
+		if err != nil {
+			t.Fatalf("ReadExport() error = %v", err)
+		}
+		got, err := ImportTasks(dst, tasks)
+		if err != nil {
+			t.Fatalf("ImportTasks() error = %v", err)
+		}
+		return got
+	}
+
+	// Task 1 is taken by a task of dst, so "write report" moves to 3.
+	got := roundTrip()
+	if got.Added != 2 || got.Updated != 0 || len(got.Renumbered) != 1 || got.Renumbered[0].To != 3 {
+		t.Errorf("first import = %+v, want 2 added and #1 renumbered to 3", got)
+	}
+	if task, _ := dst.Get(mine.ID); task.Title != "water plants" {
+		t.Errorf("task %d of dst = %q, want it untouched", mine.ID, task.Title)
+	}
+
+	// Importing again matches by UUID and only takes newer copies.
+	title := "write annual report"
+	time.Sleep(time.Millisecond)
+	src.Update(report.ID, UpdateOptions{Title: &title})
+	got = roundTrip()
+	if got.Added != 0 || got.Updated != 1 || got.Unchanged != 1 {
+		t.Errorf("second import = %+v, want 1 updated and 1 unchanged", got)
+	}
+	if task, _ := dst.Get(3); task.Title != title || task.UUID != report.UUID {
+		t.Errorf("task 3 of dst = %+v, want the new title under the UUID of %q", task, report.Title)
+	}
+	if n := len(dst.List()); n != 3 {
+		t.Errorf("len(List()) = %d, want 3", n)
+	}
+}
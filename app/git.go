@@ -0,0 +1,309 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultGitRemote is the remote a git-backed store syncs with by default.
+const DefaultGitRemote = "origin"
+
+// ErrNotGitBacked is returned by git operations on a store opened without WithGit.
+var ErrNotGitBacked = errors.New("task store is not git-backed")
+
+// gitIgnore keeps the files that only matter to the local process out of
+// the repository of a git-backed store.
+const gitIgnore = `# Written by taskman: only the snapshot is versioned.
+*.journal
+*.lock
+.tasks-*.tmp
+backups/
+`
+
+// gitMergeDriver is the name under which "taskman merge" is registered as
+// a merge driver; gitAttributes routes the store files to it.
+const (
+	gitMergeDriver = "taskman"
+	gitAttributes  = "*.json merge=" + gitMergeDriver + "\n"
+)
+
+// gitRepo runs git in the directory of a git-backed store. Each mutation of
+// the store is committed; see Store.Sync for exchanging them with a remote.
+type gitRepo struct {
+	dir string
+}
+
+// openGitRepo returns the repository rooted at dir, initializing one if
+// dir is not under version control yet. A dir inside some other
+// repository is refused, so tasks never end up in an unrelated project.
+func openGitRepo(dir string) (*gitRepo, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git-backed store: %w", err)
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(abs, 0o755); err != nil {
+		return nil, fmt.Errorf("mkdir store dir: %w", err)
+	}
+	g := &gitRepo{dir: abs}
+
+	top, err := g.run("rev-parse", "--show-toplevel")
+	switch {
+	case err != nil:
+		if _, err := g.run("init", "--quiet"); err != nil {
+			return nil, err
+		}
+	case !sameDir(top, abs):
+		return nil, fmt.Errorf("git-backed store: %s is inside the git repository %s", abs, top)
+	}
+
+	for name, content := range map[string]string{".gitignore": gitIgnore, ".gitattributes": gitAttributes} {
+		path := filepath.Join(abs, name)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				return nil, fmt.Errorf("write %s: %w", name, err)
+			}
+		}
+	}
+	return g, nil
+}
+
+// run executes git with args and returns its trimmed standard output.
+func (g *gitRepo) run(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = g.dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// commit records the current state of files, if it changed, with message.
+// Nothing is committed before the files exist, so a new store can still
+// take over the history of a remote.
+func (g *gitRepo) commit(message string, files ...string) error {
+	var existing []string
+	for _, f := range files {
+		if _, err := os.Stat(filepath.Join(g.dir, f)); err == nil {
+			existing = append(existing, f)
+		}
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+	if _, err := g.run(append([]string{"add", "--", ".gitignore", ".gitattributes"}, existing...)...); err != nil {
+		return err
+	}
+	if _, err := g.run("diff", "--cached", "--quiet"); err == nil {
+		return nil // nothing changed
+	}
+	_, err := g.run(g.withIdentity("commit", "--quiet", "--no-verify", "-m", message)...)
+	return err
+}
+
+// withIdentity prefixes args with a committer identity if git has none
+// configured, so commits still work on a fresh machine.
+func (g *gitRepo) withIdentity(args ...string) []string {
+	if email, _ := g.run("config", "user.email"); email != "" {
+		return args
+	}
+	return append([]string{"-c", "user.name=taskman", "-c", "user.email=taskman@localhost"}, args...)
+}
+
+// hasCommits reports whether the current branch has any commit yet.
+func (g *gitRepo) hasCommits() bool {
+	_, err := g.run("rev-parse", "--quiet", "--verify", "HEAD")
+	return err == nil
+}
+
+// branch returns the name of the current branch, even before its first commit.
+func (g *gitRepo) branch() (string, error) {
+	return g.run("symbolic-ref", "--short", "HEAD")
+}
+
+// pull fetches branch from remote and merges it into the current branch.
+// It reports whether anything was merged. A conflicting merge is aborted.
+func (g *gitRepo) pull(remote, branch string) (bool, error) {
+	if _, err := g.run("fetch", "--quiet", remote); err != nil {
+		return false, err
+	}
+	ref := "refs/remotes/" + remote + "/" + branch
+	if _, err := g.run("rev-parse", "--quiet", "--verify", ref); err != nil {
+		return false, nil // the remote has no such branch yet
+	}
+	if !g.hasCommits() {
+		// Only our own .gitignore can be in the way.
+		_, err := g.run("checkout", "--quiet", "--force", "-B", branch, ref)
+		return err == nil, err
+	}
+	before, _ := g.run("rev-parse", "HEAD")
+	if _, err := g.run(g.withIdentity("merge", "--quiet", "--no-edit", "--allow-unrelated-histories", ref)...); err != nil {
+		g.run("merge", "--abort")
+		return false, fmt.Errorf("merge %s/%s: %w", remote, branch, err)
+	}
+	after, _ := g.run("rev-parse", "HEAD")
+	return before != after, nil
+}
+
+// push sends the current branch to remote.
+func (g *gitRepo) push(remote, branch string) error {
+	if !g.hasCommits() {
+		return nil
+	}
+	_, err := g.run("push", "--quiet", remote, "HEAD:refs/heads/"+branch)
+	return err
+}
+
+// setRemote points remote at url, adding it if needed.
+func (g *gitRepo) setRemote(remote, url string) error {
+	if _, err := g.run("remote", "get-url", remote); err == nil {
+		_, err = g.run("remote", "set-url", remote, url)
+		return err
+	}
+	_, err := g.run("remote", "add", remote, url)
+	return err
+}
+
+func sameDir(a, b string) bool {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(ai, bi)
+}
+
+// describeUnsafe turns journal entries into a commit message, e.g.
+// "complete #12 Write report". It runs before the entries are applied, so
+// purged tasks can still be named.
+func (s *Store) describeUnsafe(entries []journalEntry) string {
+	lines := make([]string, 0, len(entries))
+	for _, e := range entries {
+		lines = append(lines, s.describeEntryUnsafe(e))
+	}
+	if len(lines) == 1 {
+		return lines[0]
+	}
+	return fmt.Sprintf("%d changes\n\n%s", len(lines), strings.Join(lines, "\n"))
+}
+
+func (s *Store) describeEntryUnsafe(e journalEntry) string {
+	t := e.Task
+	if t == nil {
+		t = s.findTrashUnsafe(e.ID)
+	}
+	if t == nil {
+		t = s.findUnsafe(e.ID)
+	}
+	if t == nil {
+		return fmt.Sprintf("%s #%d", e.Op, e.ID)
+	}
+	op := e.Op
+	if op == opComplete && !t.IsCompleted() {
+		op = "reopen"
+	}
+	return fmt.Sprintf("%s #%d %s", op, t.ID, t.Title)
+}
+
+// gitCommitUnsafe commits the snapshot and archive of a git-backed store,
+// if they changed. The caller holds the lock and has compacted the journal.
+func (s *Store) gitCommitUnsafe(message string) error {
+	if s.git == nil {
+		return nil
+	}
+	archive, err := filepath.Rel(filepath.Dir(s.path), s.archiveDir())
+	if err != nil {
+		return err
+	}
+	if err := s.git.commit(message, filepath.Base(s.path), archive); err != nil {
+		return fmt.Errorf("commit tasks: %w", err)
+	}
+	return nil
+}
+
+// SetGitMergeDriver registers command, e.g. "taskman merge %O %A %B", as
+// the merge driver of the store files, so Sync merges diverged copies task
+// by task instead of line by line. See MergeFiles.
+func (s *Store) SetGitMergeDriver(command string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.git == nil {
+		return ErrNotGitBacked
+	}
+	if _, err := s.git.run("config", "merge."+gitMergeDriver+".name", "taskman task merge"); err != nil {
+		return err
+	}
+	_, err := s.git.run("config", "merge."+gitMergeDriver+".driver", command)
+	return err
+}
+
+// SetGitRemote points the named remote of a git-backed store at url.
+func (s *Store) SetGitRemote(remote, url string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.git == nil {
+		return ErrNotGitBacked
+	}
+	return s.git.setRemote(remote, url)
+}
+
+// Sync exchanges changes with remote: it commits the store, merges the
+// remote's branch of the same name and pushes the result. It reports
+// whether changes from the remote were merged.
+func (s *Store) Sync(remote string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.git == nil {
+		return false, ErrNotGitBacked
+	}
+	if err := s.beginUnsafe(); err != nil {
+		return false, err
+	}
+	defer s.unlockUnsafe()
+
+	if s.pending > 0 {
+		if err := s.compactUnsafe(); err != nil {
+			return false, err
+		}
+	}
+	if err := s.gitCommitUnsafe("save tasks"); err != nil {
+		return false, err
+	}
+
+	branch, err := s.git.branch()
+	if err != nil {
+		return false, err
+	}
+	merged, err := s.git.pull(remote, branch)
+	if err != nil {
+		return false, err
+	}
+	if merged {
+		s.disk = diskState{}
+		if _, err := s.syncUnsafe(); err != nil {
+			return false, err
+		}
+	}
+	return merged, s.git.push(remote, branch)
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := (&gitRepo{dir: dir}).run(args...)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	return out
+}
+
+func TestGitStore(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	s, err := Load(filepath.Join(dir, "tasks.json"), WithGit())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer s.Close()
+	task, _ := s.Add("Write report", "", nil, day)
+	s.MarkCompleted(task.ID, true)
+
+	log := gitOutput(t, dir, "log", "--format=%s")
+	want := "complete #1 Write report\nadd #1 Write report"
+	if log != want {
+		t.Errorf("git log = %q, want %q", log, want)
+	}
+	if files := gitOutput(t, dir, "ls-files"); files != ".gitattributes\n.gitignore\ntasks.json" {
+		t.Errorf("git ls-files = %q", files)
+	}
+}
+
+func TestGitSync(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	if out, err := exec.Command("git", "init", "--quiet", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare: %v: %s", err, out)
+	}
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	open := func() *Store {
+		s, err := Load(filepath.Join(t.TempDir(), "tasks.json"), WithGit())
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		t.Cleanup(func() { s.Close() })
+		if err := s.SetGitRemote(DefaultGitRemote, remote); err != nil {
+			t.Fatalf("SetGitRemote() error = %v", err)
+		}
+		return s
+	}
+	sync := func(s *Store) {
+		t.Helper()
+		if _, err := s.Sync(DefaultGitRemote); err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+	}
+
+	a := open()
+	a.Add("first", "", nil, day)
+	a.Add("second", "", nil, day)
+	sync(a)
+
+	b := open()
+	sync(b)
+	if got := len(b.List()); got != 2 {
+		t.Fatalf("len(List()) after first sync = %d, want 2", got)
+	}
+	b.MarkCompleted(1, true)
+	sync(b)
+
+	sync(a)
+	if got, err := a.Get(1); err != nil || !got.IsCompleted() {
+		t.Errorf("task completed on the other side is not completed after sync")
+	}
+	if log := gitOutput(t, a.git.dir, "log", "--format=%s"); !strings.HasPrefix(log, "complete #1 first") {
+		t.Errorf("git log = %q, want the completion on top", log)
+	}
+}
@@ -0,0 +1,255 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultHistoryLimit is how many changes a History keeps for undo.
+const DefaultHistoryLimit = 100
+
+// ErrNothingToUndo and ErrNothingToRedo are returned when the respective
+// history stack is empty.
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// change is one recorded mutation: the task as it was before and after.
+// A nil before means the task was added, a nil after that it was deleted.
+type change struct {
+	label  string
+	before *Task
+	after  *Task
+}
+
+// History wraps a TaskStore and records every mutation made through it, so
+// it can be undone and redone. Undo restores the exact earlier state of a
+// task, including its ID and timestamps.
+//
+// Before reverting a change History checks that the task is still in the
+// state the change left it in; if another process has touched it since, the
+// change is dropped and ErrConflict returned instead of overwriting theirs.
+type History struct {
+	TaskStore
+
+	mu    sync.Mutex
+	limit int
+	undo  []change
+	redo  []change
+}
+
+// NewHistory returns a History over store that keeps up to limit changes.
+// A limit of zero or less means DefaultHistoryLimit.
+func NewHistory(store TaskStore, limit int) *History {
+	if limit <= 0 {
+		limit = DefaultHistoryLimit
+	}
+	return &History{TaskStore: store, limit: limit}
+}
+
+func (h *History) Add(title, notes string, due *time.Time, date time.Time) (*Task, error) {
+	t, err := h.TaskStore.Add(title, notes, due, date)
+	if err != nil {
+		return nil, err
+	}
+	h.record(change{label: describe("add", t), after: t})
+	return t, nil
+}
+
+// AddWith adds a task with the fields of u set, e.g. its tags, as a single
+// step of the history. The task is put back with them in the batch that
+// adds it, so they are part of it from the start rather than a change.
+func (h *History) AddWith(title, notes string, due *time.Time, date time.Time, u UpdateOptions) (*Task, error) {
+	var t *Task
+	err := Batch(h.TaskStore, func(tx TaskStore) error {
+		added, err := tx.Add(title, notes, due, date)
+		if err != nil {
+			return err
+		}
+		if u == (UpdateOptions{}) {
+			t = added
+			return nil
+		}
+		cp := cloneTask(added)
+		if err := updateTask(u)(cp, cp.CreatedAt); err != nil {
+			return err
+		}
+		t, err = tx.Put(cp)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	h.record(change{label: describe("add", t), after: t})
+	return t, nil
+}
+
+func (h *History) Update(id int, u UpdateOptions) (*Task, error) {
+	return h.mutate(id, "edit", func() (*Task, error) { return h.TaskStore.Update(id, u) })
+}
+
+func (h *History) MarkCompleted(id int, completed bool) (*Task, error) {
+	return h.mutate(id, "", func() (*Task, error) { return h.TaskStore.MarkCompleted(id, completed) })
+}
+
+func (h *History) ToggleCompleted(id int) (*Task, error) {
+	return h.mutate(id, "", func() (*Task, error) { return h.TaskStore.ToggleCompleted(id) })
+}
+
+func (h *History) Delete(id int) error {
+	before, err := h.TaskStore.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := h.TaskStore.Delete(id); err != nil {
+		return err
+	}
+	h.record(change{label: describe("delete", before), before: before})
+	return nil
+}
+
+// mutate runs fn on task id and records the change. An empty verb means
+// the change is named after the completion state it left the task in.
+func (h *History) mutate(id int, verb string, fn func() (*Task, error)) (*Task, error) {
+	before, _ := h.TaskStore.Get(id)
+	t, err := fn()
+	if err != nil {
+		return nil, err
+	}
+	if verb == "" {
+		verb = "complete"
+		if !t.IsCompleted() {
+			verb = "reopen"
+		}
+	}
+	h.record(change{label: describe(verb, t), before: before, after: t})
+	return t, nil
+}
+
+// record pushes c onto the undo stack, dropping the oldest change past the
+// limit. A new change makes everything that was undone unreachable.
+func (h *History) record(c change) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.undo = append(h.undo, c)
+	if len(h.undo) > h.limit {
+		h.undo = h.undo[len(h.undo)-h.limit:]
+	}
+	h.redo = nil
+}
+
+// Undo reverts the most recent change and returns its description.
+func (h *History) Undo() (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.undo) == 0 {
+		return "", ErrNothingToUndo
+	}
+	c := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+
+	restored, err := h.apply(c.after, c.before)
+	if err != nil {
+		return "", err
+	}
+	h.rebase(c.before, restored)
+	h.redo = append(h.redo, change{label: c.label, before: restored, after: c.after})
+	return c.label, nil
+}
+
+// Redo reapplies the most recently undone change and returns its description.
+func (h *History) Redo() (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.redo) == 0 {
+		return "", ErrNothingToRedo
+	}
+	c := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+
+	applied, err := h.apply(c.before, c.after)
+	if err != nil {
+		return "", err
+	}
+	h.rebase(c.after, applied)
+	h.undo = append(h.undo, change{label: c.label, before: c.before, after: applied})
+	return c.label, nil
+}
+
+// apply moves a task from state from to state to, either of which may be
+// nil for "does not exist", and returns the state actually stored.
+func (h *History) apply(from, to *Task) (*Task, error) {
+	id := 0
+	if from != nil {
+		id = from.ID
+	} else if to != nil {
+		id = to.ID
+	}
+
+	cur, err := h.TaskStore.Get(id)
+	switch {
+	case err != nil && !errors.Is(err, ErrNotFound):
+		return nil, err
+	case from == nil && err == nil,
+		from != nil && err != nil,
+		from != nil && cur.Revision != from.Revision:
+		return nil, fmt.Errorf("task %d: %w", id, ErrConflict)
+	}
+
+	if to == nil {
+		return nil, h.TaskStore.Delete(id)
+	}
+	// The task keeps the history it has now, and the revert is added to
+	// it; a deleted task is found in the trash.
+	if from == nil {
+		cur = h.trashed(id)
+	}
+	if cur != nil {
+		t := cloneTask(to)
+		t.Changes = cur.Changes
+		recordChanges(cur, t, time.Now())
+		to = t
+	}
+	return h.TaskStore.Put(to)
+}
+
+// trashed returns task id from the trash of the store, or nil.
+func (h *History) trashed(id int) *Task {
+	if tr, ok := h.TaskStore.(Trasher); ok {
+		for _, t := range tr.Trash() {
+			if t.ID == id {
+				return t
+			}
+		}
+	}
+	return nil
+}
+
+// rebase points recorded changes that refer to a task in state old at
+// stored, the same state written again under a new revision.
+func (h *History) rebase(old, stored *Task) {
+	if old == nil || stored == nil {
+		return
+	}
+	for _, stack := range [][]change{h.undo, h.redo} {
+		for i := range stack {
+			c := &stack[i]
+			if c.before != nil && c.before.ID == old.ID && c.before.Revision == old.Revision {
+				c.before = stored
+			}
+			if c.after != nil && c.after.ID == old.ID && c.after.Revision == old.Revision {
+				c.after = stored
+			}
+		}
+	}
+}
+
+func describe(verb string, t *Task) string {
+	return fmt.Sprintf("%s %q", verb, t.Title)
+}
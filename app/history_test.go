@@ -0,0 +1,139 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryUndoRedo(t *testing.T) {
+	for _, backend := range []string{BackendJSON, BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			store, err := Open(backend, filepath.Join(t.TempDir(), "tasks"+fileExt(backend)))
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			t.Cleanup(func() { store.(io.Closer).Close() })
+			h := NewHistory(store, 0)
+			day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+			a, _ := h.Add("write report", "", nil, day)
+			if _, err := h.ToggleCompleted(a.ID); err != nil {
+				t.Fatalf("ToggleCompleted() error = %v", err)
+			}
+			done, _ := h.Get(a.ID)
+			if err := h.Delete(a.ID); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+
+			label, err := h.Undo()
+			if err != nil {
+				t.Fatalf("Undo() error = %v", err)
+			}
+			if label != `delete "write report"` {
+				t.Errorf("Undo() label = %q", label)
+			}
+			got, err := h.Get(a.ID)
+			if err != nil {
+				t.Fatalf("Get() after undo error = %v", err)
+			}
+			if !got.CreatedAt.Equal(a.CreatedAt) || got.CompletedAt == nil || !got.CompletedAt.Equal(*done.CompletedAt) {
+				t.Errorf("restored task = %+v, want timestamps of %+v", got, done)
+			}
+
+			if _, err := h.Undo(); err != nil {
+				t.Fatalf("Undo() error = %v", err)
+			}
+			if got, _ := h.Get(a.ID); got.IsCompleted() {
+				t.Errorf("task still completed after undoing the toggle")
+			}
+
+			if _, err := h.Redo(); err != nil {
+				t.Fatalf("Redo() error = %v", err)
+			}
+			if _, err := h.Redo(); err != nil {
+				t.Fatalf("Redo() error = %v", err)
+			}
+			if _, err := h.Get(a.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() after redoing delete error = %v, want ErrNotFound", err)
+			}
+			if _, err := h.Redo(); !errors.Is(err, ErrNothingToRedo) {
+				t.Errorf("Redo() error = %v, want ErrNothingToRedo", err)
+			}
+		})
+	}
+}
+
+func TestHistoryConflict(t *testing.T) {
+	store, _ := Load(filepath.Join(t.TempDir(), "tasks.json"))
+	h := NewHistory(store, 0)
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	a, _ := h.Add("write report", "", nil, day)
+	if _, err := h.ToggleCompleted(a.ID); err != nil {
+		t.Fatalf("ToggleCompleted() error = %v", err)
+	}
+	// A change that bypasses the history, as another process would make.
+	title := "edited elsewhere"
+	if _, err := store.Update(a.ID, UpdateOptions{Title: &title}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	if _, err := h.Undo(); !errors.Is(err, ErrConflict) {
+		t.Errorf("Undo() error = %v, want ErrConflict", err)
+	}
+	if got, _ := h.Get(a.ID); got.Title != title {
+		t.Errorf("Title = %q, want %q", got.Title, title)
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	store, _ := Load(filepath.Join(t.TempDir(), "tasks.json"))
+	h := NewHistory(store, 2)
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, title := range []string{"a", "b", "c"} {
+		h.Add(title, "", nil, day)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := h.Undo(); err != nil {
+			t.Fatalf("Undo() #%d error = %v", i+1, err)
+		}
+	}
+	if _, err := h.Undo(); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("Undo() past limit error = %v, want ErrNothingToUndo", err)
+	}
+	if got := len(h.List()); got != 1 {
+		t.Errorf("len(List()) = %d, want 1", got)
+	}
+}
+
+func TestHistoryAddWith(t *testing.T) {
+	store, _ := Load(filepath.Join(t.TempDir(), "tasks.json"))
+	h := NewHistory(store, 0)
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tags := []string{"work"}
+	a, err := h.AddWith("a", "", nil, day, UpdateOptions{Tags: &tags})
+	if err != nil || !a.HasTag("work") {
+		t.Fatalf("AddWith() = %+v, %v, want a task tagged work", a, err)
+	}
+	if len(a.Changes) != 0 {
+		t.Errorf("AddWith() changes = %+v, want none, the tags are part of the new task", a.Changes)
+	}
+	invalid := Priority(9)
+	if _, err := h.AddWith("b", "", nil, day, UpdateOptions{Priority: &invalid}); err == nil {
+		t.Errorf("AddWith() of an invalid priority succeeded")
+	}
+	if got := len(h.List()); got != 1 {
+		t.Errorf("len(List()) after a failed AddWith() = %d, want 1", got)
+	}
+	if label, err := h.Undo(); err != nil || label != `add "a"` {
+		t.Fatalf("Undo() = %q, %v, want the add undone in one step", label, err)
+	}
+	if got := len(h.List()); got != 0 {
+		t.Errorf("len(List()) = %d, want 0", got)
+	}
+}
@@ -0,0 +1,196 @@
+package app
+
+import (
+	"sort"
+	"time"
+)
+
+// taskIndex keeps the live tasks of a Store by ID, by day, by project, for
+// the open ones by priority and due date and for the completed ones by the
+// day they were completed, so day views, project views and the overdue
+// list do not scan every task. It holds the same pointers as Store.tasks; tasks are
+// never changed in place, only replaced, which keeps the index in step.
+type taskIndex struct {
+	byID      map[int]*Task
+	byDay     map[string][]*Task // by dayKey of the task's day
+	days      []string           // keys of byDay, sorted
+	open      []*Task            // incomplete tasks in openOrder
+	done      map[string][]*Task // completed tasks by dayKey of CompletedAt
+	byProject map[string][]*Task // tasks in a project by its path
+}
+
+func newTaskIndex(tasks []*Task) *taskIndex {
+	x := &taskIndex{
+		byID:      make(map[int]*Task, len(tasks)),
+		byDay:     map[string][]*Task{},
+		done:      map[string][]*Task{},
+		byProject: map[string][]*Task{},
+	}
+	for _, t := range tasks {
+		x.byID[t.ID] = t
+		key := dayKey(t.Date)
+		x.byDay[key] = append(x.byDay[key], t)
+		if t.Project != "" {
+			x.byProject[t.Project] = append(x.byProject[t.Project], t)
+		}
+		if !t.IsCompleted() {
+			x.open = append(x.open, t)
+		} else {
+			key := dayKey(*t.CompletedAt)
+			x.done[key] = append(x.done[key], t)
+		}
+	}
+	for key := range x.byDay {
+		x.days = append(x.days, key)
+	}
+	sort.Strings(x.days)
+	sort.Slice(x.open, func(i, j int) bool { return openOrder(x.open[i], x.open[j]) })
+	return x
+}
+
+// openOrder orders open tasks by priority, highest first, then by due
+// date, those without one last, then by ID.
+func openOrder(a, b *Task) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	if (a.Due == nil) != (b.Due == nil) {
+		return a.Due != nil
+	}
+	if a.Due != nil && !a.Due.Equal(*b.Due) {
+		return a.Due.Before(*b.Due)
+	}
+	return a.ID < b.ID
+}
+
+// put adds t, replacing the task with its ID.
+func (x *taskIndex) put(t *Task) {
+	x.remove(t.ID)
+	x.byID[t.ID] = t
+
+	key := dayKey(t.Date)
+	if _, ok := x.byDay[key]; !ok {
+		i := sort.SearchStrings(x.days, key)
+		x.days = append(x.days, "")
+		copy(x.days[i+1:], x.days[i:])
+		x.days[i] = key
+	}
+	x.byDay[key] = append(x.byDay[key], t)
+	if t.Project != "" {
+		x.byProject[t.Project] = append(x.byProject[t.Project], t)
+	}
+
+	if !t.IsCompleted() {
+		i := sort.Search(len(x.open), func(i int) bool { return openOrder(t, x.open[i]) })
+		x.open = append(x.open, nil)
+		copy(x.open[i+1:], x.open[i:])
+		x.open[i] = t
+	} else {
+		key := dayKey(*t.CompletedAt)
+		x.done[key] = append(x.done[key], t)
+	}
+}
+
+// remove drops the task with the given ID, if present.
+func (x *taskIndex) remove(id int) {
+	t, ok := x.byID[id]
+	if !ok {
+		return
+	}
+	delete(x.byID, id)
+
+	key := dayKey(t.Date)
+	if rest := removeTask(x.byDay[key], id); len(rest) > 0 {
+		x.byDay[key] = rest
+	} else {
+		delete(x.byDay, key)
+		i := sort.SearchStrings(x.days, key)
+		x.days = append(x.days[:i], x.days[i+1:]...)
+	}
+	if t.Project != "" {
+		if rest := removeTask(x.byProject[t.Project], id); len(rest) > 0 {
+			x.byProject[t.Project] = rest
+		} else {
+			delete(x.byProject, t.Project)
+		}
+	}
+
+	if !t.IsCompleted() {
+		i := sort.Search(len(x.open), func(i int) bool { return !openOrder(x.open[i], t) })
+		if i < len(x.open) && x.open[i].ID == id {
+			x.open = append(x.open[:i], x.open[i+1:]...)
+		}
+	} else {
+		key := dayKey(*t.CompletedAt)
+		if rest := removeTask(x.done[key], id); len(rest) > 0 {
+			x.done[key] = rest
+		} else {
+			delete(x.done, key)
+		}
+	}
+}
+
+// between returns the tasks scheduled from day from through day to, by
+// day and then sorted like List.
+func (x *taskIndex) between(from, to time.Time) []*Task {
+	var out []*Task
+	first, last := dayKey(from), dayKey(to)
+	for i := sort.SearchStrings(x.days, first); i < len(x.days) && x.days[i] <= last; i++ {
+		tasks := append([]*Task(nil), x.byDay[x.days[i]]...)
+		sortTasks(tasks)
+		out = append(out, tasks...)
+	}
+	return out
+}
+
+// completedOn returns the tasks completed on the day of day, sorted like
+// List.
+func (x *taskIndex) completedOn(day time.Time) []*Task {
+	out := append([]*Task(nil), x.done[dayKey(day)]...)
+	sortTasks(out)
+	return out
+}
+
+// inProject returns the tasks in the project with the given path or one
+// below it, or in any project if path is empty, sorted like List.
+func (x *taskIndex) inProject(path string) []*Task {
+	var out []*Task
+	for project, tasks := range x.byProject {
+		if path == "" || isProjectIn(project, path) {
+			out = append(out, tasks...)
+		}
+	}
+	sortTasks(out)
+	return out
+}
+
+// overdue returns the open tasks scheduled before the day of now, in
+// openOrder.
+func (x *taskIndex) overdue(now time.Time) []*Task {
+	var out []*Task
+	for _, t := range x.open {
+		if dayBefore(t.Date, now) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// listOpen returns the open tasks in openOrder.
+func (x *taskIndex) listOpen() []*Task {
+	return append([]*Task(nil), x.open...)
+}
+
+// dayBefore reports whether the day of a is before the day of b, each
+// taken in its own location like dayKey does.
+func dayBefore(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	if ay != by {
+		return ay < by
+	}
+	if am != bm {
+		return am < bm
+	}
+	return ad < bd
+}
@@ -0,0 +1,141 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestListRangeAndOverdue(t *testing.T) {
+	for _, backend := range []string{BackendJSON, BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			store, err := Open(backend, filepath.Join(t.TempDir(), "tasks"+fileExt(backend)))
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			t.Cleanup(func() { store.(io.Closer).Close() })
+
+			now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.Local)
+			day := func(d int) time.Time { return time.Date(2024, 5, d, 0, 0, 0, 0, time.Local) }
+			due := now.Add(-time.Hour)
+
+			a, _ := store.Add("no due", "", nil, day(1))
+			b, _ := store.Add("due", "", &due, day(8))
+			c, _ := store.Add("done", "", nil, day(8))
+			store.MarkCompleted(c.ID, true)
+			d, _ := store.Add("today", "", nil, day(10))
+			e, _ := store.Add("deleted", "", nil, day(9))
+			store.Delete(e.ID)
+
+			if got, want := ids(store.ListRange(day(1), day(8))), []int{a.ID, b.ID, c.ID}; fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("ListRange() = %v, want %v", got, want)
+			}
+			if got, want := ids(store.ListRange(day(2), day(7))), []int(nil); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("ListRange() of empty days = %v, want %v", got, want)
+			}
+			if got, want := ids(store.ListRange(day(9), day(31))), []int{d.ID}; fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("ListRange() = %v, want %v", got, want)
+			}
+			if got, want := ids(store.Overdue(now)), []int{b.ID, a.ID}; fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("Overdue() = %v, want %v", got, want)
+			}
+
+			// The indexes follow changes to the tasks.
+			store.MarkCompleted(b.ID, true)
+			store.MarkCompleted(c.ID, false)
+			if got, want := ids(store.Overdue(now)), []int{a.ID, c.ID}; fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("Overdue() after completing = %v, want %v", got, want)
+			}
+			store.Delete(a.ID)
+			if got := store.ListByDate(day(1)); len(got) != 0 {
+				t.Errorf("ListByDate() after Delete = %v, want none", got)
+			}
+		})
+	}
+}
+
+func ids(tasks []*Task) []int {
+	var out []int
+	for _, t := range tasks {
+		out = append(out, t.ID)
+	}
+	return out
+}
+
+// benchStore returns a JSON store with n tasks spread over the days before
+// and after start, four in five of them completed.
+func benchStore(b *testing.B, n int, start time.Time) *Store {
+	b.Helper()
+	s, err := Load(filepath.Join(b.TempDir(), "tasks.json"), WithCompactEvery(1<<30))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { s.Close() })
+
+	tasks := make([]*Task, n)
+	for i := range tasks {
+		date := start.AddDate(0, 0, i%2000-1000)
+		t := &Task{ID: i + 1, Date: date, Title: fmt.Sprintf("task %d", i+1), CreatedAt: date, UpdatedAt: date, Revision: 1}
+		if i%5 != 0 {
+			t.CompletedAt = &date
+		}
+		tasks[i] = t
+	}
+	if err := s.ReplaceAll(tasks); err != nil {
+		b.Fatal(err)
+	}
+	return s
+}
+
+// BenchmarkDayNavigation measures what the task view reads when moving to
+// the next or previous day with "]" and "[".
+func BenchmarkDayNavigation(b *testing.B) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+	s := benchStore(b, 100_000, start)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		day := start.AddDate(0, 0, i%60-30)
+		s.ListByDate(day)
+		s.ArchivedByDate(day)
+	}
+}
+
+func BenchmarkListRange(b *testing.B) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+	s := benchStore(b, 100_000, start)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.ListRange(start, start.AddDate(0, 0, 6))
+	}
+}
+
+func BenchmarkOverdue(b *testing.B) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+	s := benchStore(b, 100_000, start)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Overdue(start)
+	}
+}
+
+func BenchmarkCompletedOn(b *testing.B) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+	s := benchStore(b, 100_000, start)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.CompletedOn(start)
+	}
+}
+
+func BenchmarkToggleCompleted(b *testing.B) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+	s := benchStore(b, 100_000, start)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.ToggleCompleted(1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Journal operations. Every entry that carries a task carries its full
+// state, so replaying an entry twice has the same effect as replaying it once.
+const (
+	opAdd       = "add"
+	opUpdate    = "update"
+	opComplete  = "complete"
+	opDelete    = "delete"    // moves the task to the trash
+	opRestore   = "restore"   // moves the task out of the trash
+	opPurge     = "purge"     // removes the task from the trash for good
+	opArchive   = "archive"   // moves the task into its archive file
+	opUnarchive = "unarchive" // moves the task out of its archive file
+)
+
+// journalEntry is a single line of the journal file.
+type journalEntry struct {
+	Op   string    `json:"op"`
+	At   time.Time `json:"at"`
+	ID   int       `json:"id,omitempty"`   // purge; delete in journals written before the trash
+	Task *Task     `json:"task,omitempty"` // all others
+}
+
+func (s *Store) journalPath() string {
+	return s.path + ".journal"
+}
+
+// commitUnsafe appends entries to the journal, applies them in memory and
+// compacts when the journal has grown enough. The caller holds the lock.
+func (s *Store) commitUnsafe(entries ...journalEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	if s.auto != nil && s.auto.due() && len(s.tasks) > 0 {
+		if _, err := s.auto.backups.Create(s.tasks); err != nil {
+			return err
+		}
+	}
+	if err := s.appendJournalUnsafe(entries); err != nil {
+		return err
+	}
+	var message string
+	if s.git != nil {
+		message = s.describeUnsafe(entries)
+	}
+	for _, e := range entries {
+		s.applyUnsafe(e)
+		s.publish(e.Op, e.Task)
+	}
+	s.pending += len(entries)
+	if err := s.recordDiskUnsafe(); err != nil {
+		return err
+	}
+
+	if s.needsCompactUnsafe() {
+		if err := s.compactUnsafe(); err != nil {
+			return err
+		}
+	}
+	return s.gitCommitUnsafe(message)
+}
+
+// applyUnsafe replays a journal entry on the in-memory tasks.
+func (s *Store) applyUnsafe(e journalEntry) {
+	switch e.Op {
+	case opAdd, opUpdate, opComplete, opRestore, opUnarchive:
+		if e.Task == nil {
+			return
+		}
+		t := cloneTask(e.Task)
+		s.trash = removeTask(s.trash, t.ID)
+		s.putTaskUnsafe(t)
+		if t.ID >= s.NextID {
+			s.NextID = t.ID + 1
+		}
+		// The ID stays taken once the task is purged, before the next
+		// snapshot records it as NextID.
+		s.idFloor = max(s.idFloor, t.ID+1)
+		if e.Op == opUnarchive {
+			delete(s.archive, t.Date.Year())
+		}
+
+	case opDelete:
+		if e.Task == nil {
+			// Entries from before the trash carry only the ID.
+			s.dropTaskUnsafe(e.ID)
+			return
+		}
+		t := cloneTask(e.Task)
+		s.dropTaskUnsafe(t.ID)
+		s.trash = upsertTask(s.trash, t)
+		if t.ID >= s.NextID {
+			s.NextID = t.ID + 1
+		}
+
+	case opPurge:
+		s.trash = removeTask(s.trash, e.ID)
+		s.idFloor = max(s.idFloor, e.ID+1)
+
+	case opArchive:
+		if e.Task == nil {
+			return
+		}
+		s.dropTaskUnsafe(e.Task.ID)
+		s.idFloor = max(s.idFloor, e.Task.ID+1)
+		delete(s.archive, e.Task.Date.Year())
+	}
+}
+
+// putTaskUnsafe stores t in place of the live task with its ID.
+func (s *Store) putTaskUnsafe(t *Task) {
+	s.tasks = upsertTask(s.tasks, t)
+	s.index.put(t)
+}
+
+// dropTaskUnsafe removes the live task with the given ID, if present.
+func (s *Store) dropTaskUnsafe(id int) {
+	s.tasks = removeTask(s.tasks, id)
+	s.index.remove(id)
+}
+
+// upsertTask replaces the task with t's ID in list, or appends t.
+func upsertTask(list []*Task, t *Task) []*Task {
+	for i, cur := range list {
+		if cur.ID == t.ID {
+			list[i] = t
+			return list
+		}
+	}
+	return append(list, t)
+}
+
+// removeTask drops the task with the given ID from list, if present.
+func removeTask(list []*Task, id int) []*Task {
+	for i, cur := range list {
+		if cur.ID == id {
+			return append(list[:i], list[i+1:]...)
+		}
+	}
+	return list
+}
+
+// appendJournalUnsafe writes entries as one JSON line each and syncs them.
+// A failed write is rolled back so the journal never keeps a partial batch.
+func (s *Store) appendJournalUnsafe(entries []journalEntry) error {
+	if s.journal == nil {
+		if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+			return fmt.Errorf("mkdir store dir: %w", err)
+		}
+		f, err := os.OpenFile(s.journalPath(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("open journal: %w", err)
+		}
+		s.journal = f
+	}
+
+	var buf bytes.Buffer
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("encode journal: %w", err)
+		}
+		if line, err = sealWith(s.opts.secret, line); err != nil {
+			return fmt.Errorf("encrypt journal: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	info, err := s.journal.Stat()
+	if err != nil {
+		return fmt.Errorf("stat journal: %w", err)
+	}
+	if _, err := s.journal.Write(buf.Bytes()); err != nil {
+		_ = s.journal.Truncate(info.Size())
+		return fmt.Errorf("write journal: %w", err)
+	}
+	if err := s.journal.Sync(); err != nil {
+		_ = s.journal.Truncate(info.Size())
+		return fmt.Errorf("sync journal: %w", err)
+	}
+	return nil
+}
+
+// needsCompactUnsafe reports whether the journal should be folded into the
+// snapshot. Git-backed stores do it on every change, as only the snapshot
+// is committed.
+func (s *Store) needsCompactUnsafe() bool {
+	return s.pending > 0 && (s.git != nil || s.opts.compactEvery <= 0 || s.pending >= s.opts.compactEvery)
+}
+
+// compactUnsafe folds the journal into the snapshot. The snapshot is written
+// first, so a crash in between only leaves entries that replay idempotently.
+// The journal is truncated rather than removed because other processes may
+// hold it open for appending.
+func (s *Store) compactUnsafe() error {
+	if err := s.saveUnsafe(); err != nil {
+		return err
+	}
+	if err := os.Truncate(s.journalPath(), 0); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("truncate journal: %w", err)
+	}
+	s.pending = 0
+	return s.recordDiskUnsafe()
+}
+
+// readJournal returns every complete entry in the journal at path, starting
+// at byte offset, and the offset just past the last one. Reading stops at the
+// first torn or corrupt line, which is cut off so later appends start from
+// the last good entry. Encrypted lines are opened with secret; one that
+// cannot be opened fails the read rather than being cut off.
+func readJournal(path string, offset int64, secret *Secret) ([]journalEntry, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, 0, nil
+		}
+		return nil, 0, fmt.Errorf("open journal: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, 0, fmt.Errorf("seek journal: %w", err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, 0, fmt.Errorf("read journal: %w", err)
+	}
+
+	var entries []journalEntry
+	valid := 0
+	for valid < len(data) {
+		end := bytes.IndexByte(data[valid:], '\n')
+		if end == -1 {
+			break // incomplete last line
+		}
+		line := data[valid : valid+end]
+		if isSealed(line) && json.Valid(line) {
+			var err error
+			if line, err = unseal(secret, line); err != nil {
+				return nil, 0, fmt.Errorf("read journal: %w", err)
+			}
+		}
+		var e journalEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			break
+		}
+		entries = append(entries, e)
+		valid += end + 1
+	}
+
+	end := offset + int64(valid)
+	if valid < len(data) {
+		if err := os.Truncate(path, end); err != nil {
+			return nil, 0, fmt.Errorf("recover journal: %w", err)
+		}
+	}
+	return entries, end, nil
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournalReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	a, _ := s.Add("first", "", nil, day)
+	b, _ := s.Add("second", "", nil, day)
+	if _, err := s.ToggleCompleted(a.ID); err != nil {
+		t.Fatalf("ToggleCompleted() error = %v", err)
+	}
+	if err := s.Delete(b.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("snapshot written before compaction, stat error = %v", err)
+	}
+
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	tasks := reloaded.List()
+	if len(tasks) != 1 || tasks[0].ID != a.ID || !tasks[0].IsCompleted() {
+		t.Errorf("List() after replay = %+v, want completed task %d only", tasks, a.ID)
+	}
+	if trash := reloaded.Trash(); len(trash) != 1 || trash[0].ID != b.ID || trash[0].DeletedAt == nil {
+		t.Errorf("Trash() after replay = %+v, want deleted task %d", trash, b.ID)
+	}
+	// The trashed task keeps its ID reserved.
+	if reloaded.NextID != 3 {
+		t.Errorf("NextID = %d, want 3", reloaded.NextID)
+	}
+}
+
+func TestJournalTornWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+
+	s, _ := Load(path)
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := s.Add("kept", "", nil, day); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	s.journal.Close()
+
+	// Simulate a crash in the middle of writing the next entry.
+	f, _ := os.OpenFile(path+".journal", os.O_APPEND|os.O_WRONLY, 0o644)
+	f.WriteString(`{"op":"add","at":"2024-05-01T00:00:00Z","task":{"id":2,"tit`)
+	f.Close()
+
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := len(reloaded.List()); got != 1 {
+		t.Fatalf("len(List()) = %d, want 1", got)
+	}
+	if _, err := reloaded.Add("after crash", "", nil, day); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	reloaded.journal.Close()
+
+	again, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := len(again.List()); got != 2 {
+		t.Errorf("len(List()) = %d, want 2", got)
+	}
+}
+
+func TestJournalCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+
+	s, _ := Load(path, WithCompactEvery(3))
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for _, title := range []string{"a", "b", "c", "d"} {
+		if _, err := s.Add(title, "", nil, day); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if s.pending != 1 {
+		t.Errorf("pending = %d, want 1", s.pending)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if info, err := os.Stat(path + ".journal"); err == nil && info.Size() != 0 {
+		t.Errorf("journal size after Close() = %d, want 0", info.Size())
+	}
+
+	reloaded, _ := Load(path)
+	if got := len(reloaded.List()); got != 4 {
+		t.Errorf("len(List()) = %d, want 4", got)
+	}
+}
+
+// TestJournalPurgedID checks that the ID of a task purged since the last
+// snapshot is not handed out again by a process replaying the journal.
+func TestJournalPurgedID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	other, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	a, _ := s.Add("purged", "", nil, day)
+	if err := s.Delete(a.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := s.Purge(a.ID); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+
+	b, err := other.Add("after purge", "", nil, day)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if b.ID == a.ID {
+		t.Errorf("Add() in another process reused purged ID %d", a.ID)
+	}
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if reloaded.NextID <= b.ID {
+		t.Errorf("NextID after replay = %d, want more than %d", reloaded.NextID, b.ID)
+	}
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Named task lists, e.g. "work" and "personal", are separate stores kept
+// side by side: the list "work" of the store tasks.json is work.json in
+// the same directory. The store's own file is the list named after it.
+
+var listNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// ValidateListName reports whether name can be used for a list. Names are
+// file names, so only letters, digits, '-' and '_' are allowed.
+func ValidateListName(name string) error {
+	if !listNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid list name %q: use letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// ListName returns the name of the list stored at path.
+func ListName(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// ListPath returns the file of the named list kept next to the store at path.
+func ListPath(path, name string) string {
+	return filepath.Join(filepath.Dir(path), name+filepath.Ext(path))
+}
+
+// Lists returns the names of the lists kept next to the store at path,
+// sorted. The store's own list is included even before it is created.
+// The lists of a markdown store, which has no extension, are the sibling
+// directories of daily notes.
+func Lists(path string) ([]string, error) {
+	files, err := filepath.Glob(ListPath(path, "*"))
+	if err != nil {
+		return nil, err
+	}
+	notes := filepath.Ext(path) == ""
+	seen := map[string]bool{ListName(path): true}
+	for _, f := range files {
+		if notes && !isNoteDir(f) {
+			continue
+		}
+		if name := ListName(f); ValidateListName(name) == nil {
+			seen[name] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
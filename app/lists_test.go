@@ -0,0 +1,77 @@
+package app
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLists(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.json")
+	for _, name := range []string{"work.json", "on-call.json", ".tasks-1.tmp", "work.json.journal", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := Lists(path)
+	if err != nil {
+		t.Fatalf("Lists() error = %v", err)
+	}
+	if want := []string{"on-call", "tasks", "work"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Lists() = %v, want %v", got, want)
+	}
+	if got := ListPath(path, "work"); got != filepath.Join(dir, "work.json") {
+		t.Errorf("ListPath() = %q", got)
+	}
+	if got := ListName(ListPath(path, "work")); got != "work" {
+		t.Errorf("ListName() = %q, want work", got)
+	}
+	for _, name := range []string{"", "../x", ".hidden", "a b", "a/b"} {
+		if err := ValidateListName(name); err == nil {
+			t.Errorf("ValidateListName(%q) succeeded", name)
+		}
+	}
+}
+
+func TestMigratePath(t *testing.T) {
+	// A store outside the default directory moves next to itself.
+	dir := filepath.Join(t.TempDir(), "lists")
+	src, err := Open(BackendJSON, filepath.Join(dir, "work.json"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer src.(io.Closer).Close()
+	if _, err := src.Add("a", "", nil, time.Now()); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	path := MigratePath(filepath.Join(dir, "work.json"), BackendJSON, BackendSQLite)
+	if want := filepath.Join(dir, "work.db"); path != want {
+		t.Fatalf("MigratePath() = %q, want %q", path, want)
+	}
+	dst, err := Open(BackendSQLite, path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer dst.(io.Closer).Close()
+	if n, err := Migrate(src, dst); err != nil || n != 1 {
+		t.Fatalf("Migrate() = %d, %v, want 1 task", n, err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("migrated store: %v", err)
+	}
+
+	for _, tc := range []struct{ path, from, to, want string }{
+		{"/x/work.db", BackendSQLite, BackendMarkdown, "/x/work"},
+		{"/x/my.notes", BackendMarkdown, BackendJSON, "/x/my.notes.json"},
+	} {
+		if got := MigratePath(tc.path, tc.from, tc.to); got != tc.want {
+			t.Errorf("MigratePath(%q, %s, %s) = %q, want %q", tc.path, tc.from, tc.to, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// diskState remembers which version of the store files the in-memory tasks
+// reflect, so changes made by other processes can be detected cheaply.
+type diskState struct {
+	snapshot os.FileInfo // nil while there is no snapshot
+	journal  int64       // journal bytes already applied
+}
+
+func (s *Store) lockPath() string {
+	return s.path + ".lock"
+}
+
+// lockUnsafe takes the advisory lock shared by every process using the store.
+func (s *Store) lockUnsafe() error {
+	if s.lockf == nil {
+		if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+			return fmt.Errorf("mkdir store dir: %w", err)
+		}
+		f, err := os.OpenFile(s.lockPath(), os.O_CREATE|os.O_RDWR, 0o644)
+		if err != nil {
+			return fmt.Errorf("open lock: %w", err)
+		}
+		s.lockf = f
+	}
+	if err := lockFile(s.lockf); err != nil {
+		return fmt.Errorf("lock store: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) unlockUnsafe() {
+	_ = unlockFile(s.lockf)
+}
+
+// beginUnsafe locks the store files and catches up with changes made by
+// other processes. On success the caller must call unlockUnsafe.
+func (s *Store) beginUnsafe() error {
+	if err := s.lockUnsafe(); err != nil {
+		return err
+	}
+	if _, err := s.syncUnsafe(); err != nil {
+		s.unlockUnsafe()
+		return err
+	}
+	return nil
+}
+
+// syncUnsafe brings the in-memory tasks up to date with the files on disk.
+// When only the journal has grown, just the new entries are applied;
+// a replaced snapshot or a shrunk journal means a full reload.
+// It reports whether anything changed.
+func (s *Store) syncUnsafe() (bool, error) {
+	snap, journal, err := s.statUnsafe()
+	if err != nil {
+		return false, err
+	}
+
+	sameSnapshot := sameFile(snap, s.disk.snapshot)
+	if sameSnapshot && journal == s.disk.journal {
+		return false, nil
+	}
+
+	offset := s.disk.journal
+	if !sameSnapshot || journal < offset {
+		if err := s.readSnapshotUnsafe(); err != nil {
+			return false, err
+		}
+		offset = 0
+		s.pending = 0
+	}
+	entries, end, err := readJournal(s.journalPath(), offset, s.opts.secret)
+	if err != nil {
+		return false, err
+	}
+	for _, e := range entries {
+		s.applyUnsafe(e)
+	}
+	s.pending += len(entries)
+	s.resetNextIDUnsafe()
+
+	s.disk = diskState{snapshot: snap, journal: end}
+	return true, nil
+}
+
+// checkUnsafe returns the current version of a task the caller last saw as
+// seen, or ErrConflict if it has since been changed or deleted elsewhere.
+func (s *Store) checkUnsafe(seen *Task) (*Task, error) {
+	cur := s.findUnsafe(seen.ID)
+	if cur == nil || cur.Revision != seen.Revision || !cur.UpdatedAt.Equal(seen.UpdatedAt) {
+		return nil, fmt.Errorf("task %d: %w", seen.ID, ErrConflict)
+	}
+	return cur, nil
+}
+
+// recordDiskUnsafe notes the current files as the ones in memory, after
+// this process wrote them itself.
+func (s *Store) recordDiskUnsafe() error {
+	snap, journal, err := s.statUnsafe()
+	if err != nil {
+		return err
+	}
+	s.disk = diskState{snapshot: snap, journal: journal}
+	return nil
+}
+
+func (s *Store) statUnsafe() (os.FileInfo, int64, error) {
+	snap, err := os.Stat(s.path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, 0, fmt.Errorf("stat store: %w", err)
+		}
+		snap = nil
+	}
+	var journal int64
+	if info, err := os.Stat(s.journalPath()); err == nil {
+		journal = info.Size()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, 0, fmt.Errorf("stat journal: %w", err)
+	}
+	return snap, journal, nil
+}
+
+func sameFile(a, b os.FileInfo) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return os.SameFile(a, b) && a.Size() == b.Size() && a.ModTime().Equal(b.ModTime())
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreConflict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	a, _ := Load(path)
+	task, err := a.Add("shared", "", nil, day)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	b, _ := Load(path)
+
+	title := "renamed by a"
+	if _, err := a.Update(task.ID, UpdateOptions{Title: &title}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := b.ToggleCompleted(task.ID); !errors.Is(err, ErrConflict) {
+		t.Fatalf("stale ToggleCompleted() error = %v, want ErrConflict", err)
+	}
+
+	// b has caught up with a and can now write.
+	got, err := b.ToggleCompleted(task.ID)
+	if err != nil {
+		t.Fatalf("ToggleCompleted() error = %v", err)
+	}
+	if got.Title != title || got.Revision != 3 {
+		t.Errorf("ToggleCompleted() = %q rev %d, want %q rev 3", got.Title, got.Revision, title)
+	}
+
+	// Adds from both processes get distinct IDs.
+	x, _ := a.Add("from a", "", nil, day)
+	y, _ := b.Add("from b", "", nil, day)
+	if x.ID == y.ID {
+		t.Errorf("Add() IDs collide: %d", x.ID)
+	}
+}
@@ -0,0 +1,910 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// The markdown store keeps tasks in a directory of daily notes, the way
+// note-taking apps like Obsidian and Logseq do: the tasks of a day are the
+// checkbox items of YYYY-MM-DD.md, e.g.
+//
+//	- [x] Write report
+//	  id:: 12
+//	  uuid:: 0f9e...
+//	  created:: 2024-05-01T09:12:44+02:00
+//	  completed:: 2024-05-01T16:03:10+02:00
+//	  The notes of the task, indented below it.
+//
+// Everything else in a note is left as it is. Tasks typed in by hand need
+// nothing but the checkbox line: they are given an ID when the store reads
+// them and their properties when their note is next written. Deleted tasks
+// go to markdownTrash, which note apps hide. Once a task with the highest
+// ID is purged, the trash also starts with the next ID, e.g.
+//
+//	next-id:: 13
+//
+// so that no ID is handed out twice.
+
+// markdownTrash is the note the trash is kept in.
+const markdownTrash = ".taskman-trash.md"
+
+// markdownTimeFormat is the format of the times in a note. They are kept
+// to the second, which is as much as anyone editing a note wants to see.
+const markdownTimeFormat = time.RFC3339
+
+var (
+	dailyNotePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}\.md$`)
+	taskLinePattern  = regexp.MustCompile(`^[-*+] \[([ xX])\](?: (.*))?$`)
+	propertyPattern  = regexp.MustCompile(`^([a-z]+):: ?(.*)$`)
+	nextIDPattern    = regexp.MustCompile(`^next-id:: ?(\d+)$`)
+)
+
+// markdownProperties are the properties the store reads from the lines
+// right below a task. Other lines, and properties it does not know, are
+// part of the notes.
+var markdownProperties = map[string]bool{
+	"id": true, "uuid": true, "date": true, "due": true, "created": true,
+	"updated": true, "completed": true, "revision": true, "deleted": true,
+	"history": true, "tags": true, "priority": true, "project": true,
+}
+
+// MarkdownStore is a task store backed by a directory of daily notes. It
+// keeps the tasks in memory and writes back only the notes a change
+// touches; notes changed by other programs are read again before the next
+// change. It is safe for concurrent use within a process.
+type MarkdownStore struct {
+	mu      sync.RWMutex
+	dir     string
+	mem     *MemoryStore         // the tasks of the notes
+	notes   map[string]*noteFile // by file name
+	saved   map[int]*Task        // the tasks of mem as the notes have them
+	floor   int                  // the next ID as the trash has it, 0 if not
+	pending []Event              // published by mem, not saved yet
+	cancel  func()               // of the subscription to mem
+	auto    *autoBackup
+	watcher *fsnotify.Watcher
+
+	subscribers
+}
+
+// noteFile is a note as it was last read or written.
+type noteFile struct {
+	blocks  []noteBlock
+	modTime time.Time
+	size    int64
+}
+
+// noteBlock is a task with its property and notes lines, or a run of
+// other lines. Only the ID of the task is used once the note is read.
+type noteBlock struct {
+	task  *Task
+	lines []string // verbatim, when task is nil
+}
+
+// OpenMarkdown opens (or creates) a task store in the directory dir.
+func OpenMarkdown(dir string, opts ...Option) (*MarkdownStore, error) {
+	o := newOptions(opts)
+	if o.secret != nil {
+		return nil, fmt.Errorf("encryption is only supported by the %s backend", BackendJSON)
+	}
+	if o.git {
+		return nil, fmt.Errorf("git-backed stores are only supported by the %s backend", BackendJSON)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("mkdir store dir: %w", err)
+	}
+	s := &MarkdownStore{dir: dir, auto: o.autoBackup(dir)}
+	if err := s.loadUnsafe(); err != nil {
+		return nil, err
+	}
+	if o.trashRetention > 0 {
+		if _, err := s.PurgeTrash(time.Now().Add(-o.trashRetention)); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Close stops watching the notes.
+func (s *MarkdownStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.watcher != nil {
+		s.watcher.Close()
+		s.watcher = nil
+	}
+	return nil
+}
+
+// tasks returns the tasks in memory for reading.
+func (s *MarkdownStore) tasks() *MemoryStore {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.mem
+}
+
+// List returns all tasks, sorted like Store.List.
+func (s *MarkdownStore) List() []*Task {
+	return s.tasks().List()
+}
+
+// ListByDate returns the tasks of the given day, sorted like List.
+func (s *MarkdownStore) ListByDate(date time.Time) []*Task {
+	return s.tasks().ListByDate(date)
+}
+
+// ListRange returns the tasks scheduled from the day of from through the
+// day of to, by day and then sorted like List.
+func (s *MarkdownStore) ListRange(from, to time.Time) []*Task {
+	return s.tasks().ListRange(from, to)
+}
+
+// Overdue returns the open tasks scheduled before the day of now.
+func (s *MarkdownStore) Overdue(now time.Time) []*Task {
+	return s.tasks().Overdue(now)
+}
+
+// ListOpen returns the open tasks.
+func (s *MarkdownStore) ListOpen() []*Task {
+	return s.tasks().ListOpen()
+}
+
+// CompletedOn returns the tasks completed on the day of day.
+func (s *MarkdownStore) CompletedOn(day time.Time) []*Task {
+	return s.tasks().CompletedOn(day)
+}
+
+// ListProject returns the tasks in the project with the given path or one
+// below it, or in any project if path is empty.
+func (s *MarkdownStore) ListProject(path string) []*Task {
+	return s.tasks().ListProject(path)
+}
+
+// Get returns a copy of the task with the given ID.
+func (s *MarkdownStore) Get(id int) (*Task, error) {
+	return s.tasks().Get(id)
+}
+
+// Trash returns the deleted tasks, most recently deleted first.
+func (s *MarkdownStore) Trash() []*Task {
+	return s.tasks().Trash()
+}
+
+// Add creates a new task in the note of its day.
+func (s *MarkdownStore) Add(title, notes string, due *time.Time, date time.Time) (*Task, error) {
+	return s.changeTask(func(m *MemoryStore) (*Task, error) { return m.Add(title, notes, due, date) })
+}
+
+// Update modifies a task, moving it to another note if its day changes.
+func (s *MarkdownStore) Update(id int, opts UpdateOptions) (*Task, error) {
+	return s.changeTask(func(m *MemoryStore) (*Task, error) { return m.Update(id, opts) })
+}
+
+// MarkCompleted sets or clears completion.
+func (s *MarkdownStore) MarkCompleted(id int, completed bool) (*Task, error) {
+	return s.changeTask(func(m *MemoryStore) (*Task, error) { return m.MarkCompleted(id, completed) })
+}
+
+// ToggleCompleted flips completion state.
+func (s *MarkdownStore) ToggleCompleted(id int) (*Task, error) {
+	return s.changeTask(func(m *MemoryStore) (*Task, error) { return m.ToggleCompleted(id) })
+}
+
+// Delete moves a task from its note into the trash.
+func (s *MarkdownStore) Delete(id int) error {
+	return s.change(func(m *MemoryStore) error { return m.Delete(id) })
+}
+
+// Put stores a fully-formed task as given.
+func (s *MarkdownStore) Put(t *Task) (*Task, error) {
+	return s.changeTask(func(m *MemoryStore) (*Task, error) { return m.Put(t) })
+}
+
+// Import adds fully-formed tasks, keeping their IDs and timestamps.
+func (s *MarkdownStore) Import(tasks []*Task) error {
+	return s.change(func(m *MemoryStore) error { return m.Import(tasks) })
+}
+
+// ReplaceAll swaps every task in the store for tasks. Trashed tasks stay
+// in the trash unless one of the new tasks takes their ID.
+func (s *MarkdownStore) ReplaceAll(tasks []*Task) error {
+	return s.change(func(m *MemoryStore) error { return m.ReplaceAll(tasks) })
+}
+
+// Restore moves a task out of the trash into the note of its day.
+func (s *MarkdownStore) Restore(id int) (*Task, error) {
+	return s.changeTask(func(m *MemoryStore) (*Task, error) { return m.Restore(id) })
+}
+
+// Purge removes a task from the trash for good.
+func (s *MarkdownStore) Purge(id int) error {
+	return s.change(func(m *MemoryStore) error { return m.Purge(id) })
+}
+
+// PurgeTrash removes every task deleted at or before the given time.
+func (s *MarkdownStore) PurgeTrash(before time.Time) (int, error) {
+	var n int
+	err := s.change(func(m *MemoryStore) (err error) {
+		n, err = m.PurgeTrash(before)
+		return err
+	})
+	return n, err
+}
+
+// Tx runs fn on the tasks in memory and writes the notes it changed once
+// it returns nil. If fn fails, the tasks are read back from the notes.
+// The store is locked until Tx returns, so fn must only use tx.
+func (s *MarkdownStore) Tx(fn func(tx TaskStore) error) error {
+	return s.change(func(m *MemoryStore) error { return fn(m) })
+}
+
+// Reload reads the notes again.
+func (s *MarkdownStore) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loadUnsafe()
+}
+
+// Watch reloads the store whenever a note is changed by another program,
+// e.g. a note-taking app, and then calls onChange. Changes made through
+// this store do not trigger it.
+func (s *MarkdownStore) Watch(onChange func()) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.watcher != nil {
+		return fmt.Errorf("store is already watched")
+	}
+	w, err := watchFiles(s.dir, isNoteName, func() {
+		if changed, err := s.refresh(); err == nil && changed {
+			onChange()
+		}
+	})
+	if err != nil {
+		return err
+	}
+	s.watcher = w
+	return nil
+}
+
+// refresh catches up with changes on disk and reports whether there were any.
+func (s *MarkdownStore) refresh() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.syncUnsafe()
+}
+
+func (s *MarkdownStore) changeTask(fn func(m *MemoryStore) (*Task, error)) (*Task, error) {
+	var t *Task
+	err := s.change(func(m *MemoryStore) (err error) {
+		t, err = fn(m)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// change catches up with the notes on disk, runs fn on the tasks in memory
+// and writes the notes whose tasks it changed. If fn fails or the notes
+// cannot be written, the tasks are read back from the notes as they are.
+// The events of the change are published once it is saved.
+func (s *MarkdownStore) change(fn func(m *MemoryStore) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.syncUnsafe(); err != nil {
+		return err
+	}
+	if err := s.backupIfDueUnsafe(); err != nil {
+		return err
+	}
+	err := fn(s.mem)
+	current, dirty := s.diffUnsafe()
+	if err == nil {
+		err = s.writeUnsafe(current, dirty)
+	}
+	events := s.pending
+	s.pending = nil
+	if err != nil {
+		if len(dirty) > 0 {
+			if loadErr := s.loadUnsafe(); loadErr != nil {
+				return errors.Join(err, loadErr)
+			}
+		}
+		return err
+	}
+	for _, e := range events {
+		s.send(e)
+	}
+	return nil
+}
+
+// backupIfDueUnsafe takes an automatic backup before a change when one is
+// due. The caller holds the lock.
+func (s *MarkdownStore) backupIfDueUnsafe() error {
+	if s.auto == nil || !s.auto.due() {
+		return nil
+	}
+	if tasks := s.mem.List(); len(tasks) > 0 {
+		if _, err := s.auto.backups.Create(tasks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncUnsafe reads the notes again if any of them changed on disk since
+// they were last read or written, and reports whether they did. The caller
+// holds the lock.
+func (s *MarkdownStore) syncUnsafe() (bool, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return false, fmt.Errorf("read store dir: %w", err)
+	}
+	seen := 0
+	changed := false
+	for _, e := range entries {
+		if e.IsDir() || !isNoteName(e.Name()) {
+			continue
+		}
+		seen++
+		info, err := e.Info()
+		if err != nil {
+			changed = true
+			break
+		}
+		note := s.notes[e.Name()]
+		if note == nil || !note.modTime.Equal(info.ModTime()) || note.size != info.Size() {
+			changed = true
+			break
+		}
+	}
+	if !changed && seen == len(s.notes) {
+		return false, nil
+	}
+	return true, s.loadUnsafe()
+}
+
+// loadUnsafe reads every note in the directory. The caller holds the lock.
+func (s *MarkdownStore) loadUnsafe() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("read store dir: %w", err)
+	}
+	notes := make(map[string]*noteFile)
+	var tasks []*Task
+	floor := 0
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !isNoteName(name) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return fmt.Errorf("read note: %w", err)
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, name))
+		if err != nil {
+			return fmt.Errorf("read note: %w", err)
+		}
+		blocks, err := parseNote(name, data, info.ModTime())
+		if err != nil {
+			return err
+		}
+		notes[name] = &noteFile{blocks: blocks, modTime: info.ModTime(), size: info.Size()}
+		for _, b := range blocks {
+			if b.task != nil {
+				tasks = append(tasks, b.task)
+			}
+		}
+		if name == markdownTrash {
+			floor = noteNextID(blocks)
+		}
+	}
+
+	// Tasks typed in by hand have no ID yet, and copied ones one that is
+	// taken. They get new ones, in the order of the notes, and are left out
+	// of saved so the next change writes them down.
+	next := max(1, floor)
+	for _, t := range tasks {
+		next = max(next, t.ID+1)
+	}
+	used := make(map[int]bool)
+	numbered := make(map[int]bool)
+	for _, t := range tasks {
+		if t.ID <= 0 || used[t.ID] {
+			t.ID = next
+			next++
+			numbered[t.ID] = true
+		}
+		used[t.ID] = true
+		if t.UUID == "" {
+			t.UUID = legacyUUID(t.ID, t.CreatedAt)
+		}
+	}
+
+	mem := NewMemoryStore()
+	if err := mem.Import(tasks); err != nil {
+		return err
+	}
+	mem.nextID = max(mem.nextID, next)
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.mem = mem
+	s.cancel = mem.Subscribe(func(e Event) { s.pending = append(s.pending, e) })
+	s.pending = nil
+	s.notes = notes
+	s.floor = floor
+	s.saved = s.currentUnsafe()
+	for id := range numbered {
+		delete(s.saved, id)
+	}
+	return nil
+}
+
+// currentUnsafe returns the tasks in memory by ID. The caller holds the lock.
+func (s *MarkdownStore) currentUnsafe() map[int]*Task {
+	// List and Trash hand out the tasks of mem themselves. Tasks are
+	// replaced rather than changed, so a changed task is a new pointer.
+	current := make(map[int]*Task)
+	for _, t := range s.mem.List() {
+		current[t.ID] = t
+	}
+	for _, t := range s.mem.Trash() {
+		current[t.ID] = t
+	}
+	return current
+}
+
+// diffUnsafe returns the tasks in memory by ID, and the names of the notes
+// whose tasks differ from saved. The trash is also written when the next ID
+// is above those of the tasks and the trash does not have it yet. The
+// caller holds the lock.
+func (s *MarkdownStore) diffUnsafe() (map[int]*Task, map[string]bool) {
+	current := s.currentUnsafe()
+	dirty := make(map[string]bool)
+	if s.nextFloorUnsafe(current) > s.floor {
+		dirty[markdownTrash] = true
+	}
+	for id, t := range current {
+		if old := s.saved[id]; old != t {
+			if old != nil {
+				dirty[noteName(old)] = true
+			}
+			dirty[noteName(t)] = true
+		}
+	}
+	for id, old := range s.saved {
+		if current[id] == nil {
+			dirty[noteName(old)] = true
+		}
+	}
+	return current, dirty
+}
+
+// nextFloorUnsafe returns the next ID if it is above those of current,
+// and 0 otherwise. The caller holds the lock.
+func (s *MarkdownStore) nextFloorUnsafe(current map[int]*Task) int {
+	maxID := 0
+	for id := range current {
+		maxID = max(maxID, id)
+	}
+	if next := s.mem.freeID(); next > maxID+1 {
+		return next
+	}
+	return 0
+}
+
+// writeUnsafe writes the dirty notes with the tasks of current and makes
+// current the saved tasks. The caller holds the lock.
+func (s *MarkdownStore) writeUnsafe(current map[int]*Task, dirty map[string]bool) error {
+	if dirty[markdownTrash] {
+		s.floor = max(s.floor, s.nextFloorUnsafe(current))
+	}
+	byNote := make(map[string]map[int]*Task)
+	for id, t := range current {
+		name := noteName(t)
+		if !dirty[name] {
+			continue
+		}
+		if byNote[name] == nil {
+			byNote[name] = make(map[int]*Task)
+		}
+		byNote[name][id] = t
+	}
+	names := make([]string, 0, len(dirty))
+	for name := range dirty {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := s.writeNoteUnsafe(name, byNote[name]); err != nil {
+			return err
+		}
+	}
+	s.saved = current
+	return nil
+}
+
+// writeNoteUnsafe rewrites the note name to hold tasks. Tasks already in
+// it stay where they are, new ones go below the last task, and a note left
+// empty is removed. The caller holds the lock.
+func (s *MarkdownStore) writeNoteUnsafe(name string, tasks map[int]*Task) error {
+	var blocks []noteBlock
+	last := -1
+	if note := s.notes[name]; note != nil {
+		for _, b := range note.blocks {
+			if b.task == nil {
+				blocks = append(blocks, b)
+				continue
+			}
+			if t := tasks[b.task.ID]; t != nil {
+				blocks = append(blocks, noteBlock{task: t})
+				last = len(blocks) - 1
+				delete(tasks, t.ID)
+			}
+		}
+	}
+	added := make([]noteBlock, 0, len(tasks))
+	for _, t := range tasks {
+		added = append(added, noteBlock{task: t})
+	}
+	sort.Slice(added, func(i, j int) bool { return added[i].task.ID < added[j].task.ID })
+	at := len(blocks)
+	if last >= 0 {
+		at = last + 1
+	}
+	blocks = append(blocks[:at], append(added, blocks[at:]...)...)
+	if name == markdownTrash {
+		blocks = withNextID(blocks, s.floor)
+	}
+
+	path := filepath.Join(s.dir, name)
+	data := renderNote(blocks)
+	if data == nil {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove note: %w", err)
+		}
+		delete(s.notes, name)
+		return nil
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("stat note: %w", err)
+	}
+	s.notes[name] = &noteFile{blocks: blocks, modTime: info.ModTime(), size: info.Size()}
+	return nil
+}
+
+// isNoteName reports whether name is a daily note or the trash.
+func isNoteName(name string) bool {
+	if name == markdownTrash {
+		return true
+	}
+	if !dailyNotePattern.MatchString(name) {
+		return false
+	}
+	_, err := time.Parse("2006-01-02", strings.TrimSuffix(name, ".md"))
+	return err == nil
+}
+
+// isNoteDir reports whether dir holds daily notes, or nothing yet.
+func isNoteDir(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, e := range entries {
+		if !e.IsDir() && isNoteName(e.Name()) {
+			return true
+		}
+	}
+	return len(entries) == 0
+}
+
+// noteName returns the note t is kept in.
+func noteName(t *Task) string {
+	if t.DeletedAt != nil {
+		return markdownTrash
+	}
+	return dayKey(t.Date) + ".md"
+}
+
+// noteNextID returns the next ID a trash note starts with, or 0.
+func noteNextID(blocks []noteBlock) int {
+	if len(blocks) == 0 || blocks[0].task != nil {
+		return 0
+	}
+	m := nextIDPattern.FindStringSubmatch(blocks[0].lines[0])
+	if m == nil {
+		return 0
+	}
+	id, _ := strconv.Atoi(m[1])
+	return id
+}
+
+// withNextID returns the blocks of a trash note starting with the next ID,
+// or without it if next is 0.
+func withNextID(blocks []noteBlock, next int) []noteBlock {
+	if noteNextID(blocks) > 0 {
+		if rest := blocks[0].lines[1:]; len(rest) > 0 {
+			blocks = append([]noteBlock{{lines: rest}}, blocks[1:]...)
+		} else {
+			blocks = blocks[1:]
+		}
+	}
+	if next == 0 {
+		return blocks
+	}
+	line := fmt.Sprintf("next-id:: %d", next)
+	if len(blocks) > 0 && blocks[0].task == nil {
+		return append([]noteBlock{{lines: append([]string{line}, blocks[0].lines...)}}, blocks[1:]...)
+	}
+	return append([]noteBlock{{lines: []string{line}}}, blocks...)
+}
+
+// noteDay returns the start of the day a daily note is named after.
+func noteDay(key string) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", key, time.Local)
+}
+
+// parseNote splits a note into its tasks and the lines around them. Checked
+// tasks without a completion time were completed when the note was last
+// modified, at modTime; so were trashed tasks without a deletion time.
+func parseNote(name string, data []byte, modTime time.Time) ([]noteBlock, error) {
+	text := strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	lines := strings.Split(text, "\n")
+
+	var day time.Time
+	if name != markdownTrash {
+		day, _ = noteDay(strings.TrimSuffix(name, ".md"))
+	}
+	var blocks []noteBlock
+	for i := 0; i < len(lines); {
+		m := taskLinePattern.FindStringSubmatch(lines[i])
+		if m == nil {
+			if n := len(blocks); n > 0 && blocks[n-1].task == nil {
+				blocks[n-1].lines = append(blocks[n-1].lines, lines[i])
+			} else {
+				blocks = append(blocks, noteBlock{lines: []string{lines[i]}})
+			}
+			i++
+			continue
+		}
+		// The task goes on as long as the lines are indented; blank lines
+		// only belong to it if an indented one follows.
+		end := i + 1
+		for j := i + 1; j < len(lines); j++ {
+			if isIndented(lines[j]) {
+				end = j + 1
+			} else if strings.TrimSpace(lines[j]) != "" {
+				break
+			}
+		}
+		t, err := parseNoteTask(m[1] != " ", m[2], lines[i+1:end], day, modTime)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, i+1, err)
+		}
+		blocks = append(blocks, noteBlock{task: t})
+		i = end
+	}
+	return blocks, nil
+}
+
+// parseNoteTask reads a task from its checkbox, title and the lines below
+// them. day is the day of its note, zero for the trash.
+func parseNoteTask(checked bool, title string, body []string, day, modTime time.Time) (*Task, error) {
+	props := make(map[string]string)
+	var history, notes []string
+	for _, line := range body {
+		line = dedent(line)
+		if notes == nil {
+			if m := propertyPattern.FindStringSubmatch(line); m != nil && markdownProperties[m[1]] {
+				if m[1] == "history" {
+					history = append(history, strings.TrimSpace(m[2]))
+				} else {
+					props[m[1]] = strings.TrimSpace(m[2])
+				}
+				continue
+			}
+		}
+		notes = append(notes, line)
+	}
+
+	t := &Task{
+		Title:    strings.TrimSpace(title),
+		Notes:    strings.TrimLeft(strings.Join(notes, "\n"), "\n"),
+		UUID:     props["uuid"],
+		Tags:     ParseTags(props["tags"]),
+		Project:  NormalizeProject(props["project"]),
+		Revision: 1,
+	}
+	var err error
+	if v, ok := props["id"]; ok {
+		if t.ID, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("parse id: %w", err)
+		}
+	}
+	if v, ok := props["priority"]; ok {
+		if t.Priority, err = ParsePriority(v); err != nil {
+			return nil, fmt.Errorf("parse priority: %w", err)
+		}
+	}
+	if v, ok := props["revision"]; ok {
+		if t.Revision, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("parse revision: %w", err)
+		}
+	}
+	for _, v := range history {
+		c, err := parseNoteChange(v)
+		if err != nil {
+			return nil, fmt.Errorf("parse history: %w", err)
+		}
+		t.Changes = append(t.Changes, c)
+	}
+	times := make(map[string]*time.Time)
+	for _, key := range []string{"date", "due", "created", "updated", "completed", "deleted"} {
+		v, ok := props[key]
+		if !ok {
+			continue
+		}
+		at, err := parseNoteTime(v)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", key, err)
+		}
+		times[key] = &at
+	}
+
+	if day.IsZero() {
+		t.DeletedAt = cloneTimePtr(times["deleted"])
+		if t.DeletedAt == nil {
+			t.DeletedAt = &modTime
+		}
+		day, _ = noteDay(dayKey(*t.DeletedAt))
+	}
+	// A task moved into another note by hand is on the day of that note.
+	t.Date = day
+	if d := times["date"]; d != nil && (t.DeletedAt != nil || dayKey(*d) == dayKey(day)) {
+		t.Date = *d
+	}
+	t.Due = times["due"]
+	t.CreatedAt = t.Date
+	if c := times["created"]; c != nil {
+		t.CreatedAt = *c
+	}
+	t.UpdatedAt = t.CreatedAt
+	if u := times["updated"]; u != nil {
+		t.UpdatedAt = *u
+	}
+	if checked {
+		t.CompletedAt = times["completed"]
+		if t.CompletedAt == nil {
+			t.CompletedAt = &modTime
+		}
+	}
+	return t, nil
+}
+
+// parseNoteTime reads a time as the store writes it, or as typed by hand:
+// a date, or a date and time in the local time zone.
+func parseNoteTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+// renderNote returns the text of a note, or nil if there is nothing but
+// blank lines left in it.
+func renderNote(blocks []noteBlock) []byte {
+	var lines []string
+	empty := true
+	for _, b := range blocks {
+		if b.task != nil {
+			lines = append(lines, noteTaskLines(b.task)...)
+			empty = false
+			continue
+		}
+		for _, line := range b.lines {
+			empty = empty && strings.TrimSpace(line) == ""
+		}
+		lines = append(lines, b.lines...)
+	}
+	if empty {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// noteTaskLines returns the lines of a task in a note. The date is only
+// written when the note does not tell it, in the trash or when it is not
+// the start of the day.
+func noteTaskLines(t *Task) []string {
+	box := " "
+	if t.IsCompleted() {
+		box = "x"
+	}
+	lines := []string{fmt.Sprintf("- [%s] %s", box, strings.ReplaceAll(t.Title, "\n", " "))}
+	prop := func(key, value string) {
+		lines = append(lines, "  "+key+":: "+value)
+	}
+	propTime := func(key string, at *time.Time) {
+		if at != nil {
+			prop(key, at.Format(markdownTimeFormat))
+		}
+	}
+
+	prop("id", strconv.Itoa(t.ID))
+	prop("uuid", t.UUID)
+	if len(t.Tags) > 0 {
+		prop("tags", strings.Join(t.Tags, ", "))
+	}
+	if t.Priority != PriorityNone {
+		prop("priority", t.Priority.String())
+	}
+	if t.Project != "" {
+		prop("project", t.Project)
+	}
+	if day, _ := noteDay(dayKey(t.Date)); t.DeletedAt != nil || !t.Date.Equal(day) {
+		propTime("date", &t.Date)
+	}
+	propTime("due", t.Due)
+	propTime("created", &t.CreatedAt)
+	propTime("updated", &t.UpdatedAt)
+	propTime("completed", t.CompletedAt)
+	prop("revision", strconv.Itoa(max(t.Revision, 1)))
+	propTime("deleted", t.DeletedAt)
+	for _, c := range t.Changes {
+		prop("history", formatNoteChange(c))
+	}
+	// Trailing blank lines would not be read back as part of the notes.
+	if notes := strings.TrimRight(t.Notes, "\n"); notes != "" {
+		for _, line := range strings.Split(notes, "\n") {
+			if line != "" {
+				line = "  " + line
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func isIndented(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+}
+
+// dedent removes one level of indentation from a line below a task.
+func dedent(line string) string {
+	for _, indent := range []string{"  ", "\t", " "} {
+		if strings.HasPrefix(line, indent) {
+			return line[len(indent):]
+		}
+	}
+	return line
+}
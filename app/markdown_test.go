@@ -0,0 +1,171 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const dailyNote = `# Wednesday
+
+Met with the team.
+
+- [ ] Call Anna
+- [x] Pay rent
+  due:: 2024-05-01 18:00
+
+  Transfer from the savings account.
+- Not a task
+`
+
+func TestMarkdownNotes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "2024-05-01.md")
+	if err := os.WriteFile(path, []byte(dailyNote), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := OpenMarkdown(dir)
+	if err != nil {
+		t.Fatalf("OpenMarkdown() error = %v", err)
+	}
+	defer s.Close()
+
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+	tasks := s.ListByDate(day)
+	if len(tasks) != 2 {
+		t.Fatalf("ListByDate() = %v, want the two tasks of the note", tasks)
+	}
+	call, rent := tasks[0], tasks[1]
+	if call.Title != "Call Anna" || call.ID != 1 || call.IsCompleted() || call.UUID == "" {
+		t.Errorf("first task = %+v, want an open Call Anna with ID 1", call)
+	}
+	due := time.Date(2024, 5, 1, 18, 0, 0, 0, time.Local)
+	if rent.Title != "Pay rent" || rent.ID != 2 || !rent.IsCompleted() || rent.Due == nil || !rent.Due.Equal(due) {
+		t.Errorf("second task = %+v, want a completed Pay rent with ID 2, due at 18:00", rent)
+	}
+	if want := "Transfer from the savings account."; rent.Notes != want {
+		t.Errorf("notes = %q, want %q", rent.Notes, want)
+	}
+
+	// Writing the note keeps the text around the tasks and puts new ones
+	// below the last task.
+	if _, err := s.Add("Buy milk", "", nil, day); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	var kept []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if !isIndented(line) {
+			kept = append(kept, line)
+		}
+	}
+	want := "# Wednesday||Met with the team.||- [ ] Call Anna|- [x] Pay rent|- [ ] Buy milk|- Not a task|"
+	if got := strings.Join(kept, "|"); got != want {
+		t.Errorf("note lines = %s, want %s", got, want)
+	}
+	if !strings.Contains(string(data), "  id:: 1\n") || strings.Contains(string(data), "date::") {
+		t.Errorf("note = %s, want tasks with IDs and without dates", data)
+	}
+
+	reopened, err := OpenMarkdown(dir)
+	if err != nil {
+		t.Fatalf("OpenMarkdown() error = %v", err)
+	}
+	again, err := reopened.Get(rent.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v; note:\n%s", err, data)
+	}
+	if again.Title != rent.Title || again.Notes != rent.Notes || again.UUID != rent.UUID || !again.CompletedAt.Equal(rent.CompletedAt.Truncate(time.Second)) {
+		t.Errorf("task after reopening = %+v, want %+v", again, rent)
+	}
+	if got := len(reopened.List()); got != 3 {
+		t.Errorf("len(List()) after reopening = %d, want 3", got)
+	}
+}
+
+func TestMarkdownMove(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenMarkdown(dir)
+	if err != nil {
+		t.Fatalf("OpenMarkdown() error = %v", err)
+	}
+	defer s.Close()
+
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+	next := day.AddDate(0, 0, 1)
+	a, _ := s.Add("a", "", nil, day)
+	if _, err := s.Update(a.ID, UpdateOptions{Date: &next}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2024-05-01.md")); !os.IsNotExist(err) {
+		t.Errorf("note of the old day is still there: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2024-05-02.md")); err != nil {
+		t.Errorf("note of the new day: %v", err)
+	}
+
+	if err := s.Delete(a.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	reopened, err := OpenMarkdown(dir)
+	if err != nil {
+		t.Fatalf("OpenMarkdown() error = %v", err)
+	}
+	trash := reopened.Trash()
+	if len(trash) != 1 || trash[0].ID != a.ID || !trash[0].Date.Equal(next) {
+		t.Fatalf("Trash() after reopening = %v, want the task of the next day", trash)
+	}
+	if _, err := reopened.Restore(a.ID); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if got := reopened.ListByDate(next); len(got) != 1 {
+		t.Errorf("ListByDate() after Restore() = %v, want the task", got)
+	}
+}
+
+func TestMarkdownExternalEdit(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenMarkdown(dir)
+	if err != nil {
+		t.Fatalf("OpenMarkdown() error = %v", err)
+	}
+	defer s.Close()
+
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+	a, _ := s.Add("a", "", nil, day)
+	b, _ := s.Add("b", "", nil, day)
+
+	// A note app renames a and adds a task of its own.
+	path := filepath.Join(dir, "2024-05-01.md")
+	data, _ := os.ReadFile(path)
+	edited := strings.Replace(string(data), "- [ ] a\n", "- [ ] a, renamed\n", 1) + "- [ ] typed by hand\n"
+	if err := os.WriteFile(path, []byte(edited), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ToggleCompleted(b.ID); err != nil {
+		t.Fatalf("ToggleCompleted() error = %v", err)
+	}
+	if got, _ := s.Get(a.ID); got.Title != "a, renamed" {
+		t.Errorf("title after the edit = %q, want the note's", got.Title)
+	}
+	if got := s.ListByDate(day); len(got) != 3 {
+		t.Errorf("ListByDate() = %v, want the task typed by hand too", got)
+	}
+	data, _ = os.ReadFile(path)
+	if !strings.Contains(string(data), "- [x] b\n") || strings.Count(string(data), "  id:: ") != 3 {
+		t.Errorf("note = %s, want b completed and every task numbered", data)
+	}
+}
+
+func TestMarkdownParseError(t *testing.T) {
+	dir := t.TempDir()
+	note := "- [ ] a\n  revision:: two\n"
+	if err := os.WriteFile(filepath.Join(dir, "2024-05-01.md"), []byte(note), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenMarkdown(dir); err == nil || !strings.Contains(err.Error(), "2024-05-01.md:1") {
+		t.Errorf("OpenMarkdown() error = %v, want one naming the line", err)
+	}
+}
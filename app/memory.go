@@ -0,0 +1,307 @@
+package app
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps tasks in memory only; nothing survives the process.
+// It is meant for tests, e.g. of the UI, that should not touch the disk.
+// It is safe for concurrent use.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	tasks  []*Task
+	index  *taskIndex // of tasks, see index.go
+	trash  []*Task
+	nextID int
+
+	subscribers
+}
+
+// NewMemoryStore returns an empty store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{index: newTaskIndex(nil), nextID: 1}
+}
+
+// List returns the tasks, sorted like Store.List.
+func (s *MemoryStore) List() []*Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*Task, len(s.tasks))
+	copy(out, s.tasks)
+	sortTasks(out)
+	return out
+}
+
+// ListByDate returns the tasks scheduled for the given day, sorted like List.
+func (s *MemoryStore) ListByDate(date time.Time) []*Task {
+	return s.ListRange(date, date)
+}
+
+// ListRange returns the tasks scheduled from the day of from through the
+// day of to, by day and then sorted like List.
+func (s *MemoryStore) ListRange(from, to time.Time) []*Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index.between(from, to)
+}
+
+// Overdue returns the open tasks scheduled before the day of now.
+func (s *MemoryStore) Overdue(now time.Time) []*Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index.overdue(now)
+}
+
+// ListOpen returns the open tasks.
+func (s *MemoryStore) ListOpen() []*Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index.listOpen()
+}
+
+// CompletedOn returns the tasks completed on the day of day.
+func (s *MemoryStore) CompletedOn(day time.Time) []*Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index.completedOn(day)
+}
+
+// ListProject returns the tasks in the project with the given path or one
+// below it, or in any project if path is empty.
+func (s *MemoryStore) ListProject(path string) []*Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index.inProject(path)
+}
+
+// Add creates a new task under the next free ID.
+func (s *MemoryStore) Add(title, notes string, due *time.Time, date time.Time) (*Task, error) {
+	if title == "" {
+		return nil, ErrTitleRequired
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	t := &Task{
+		ID:        s.nextID,
+		UUID:      newUUID(),
+		Date:      date,
+		Title:     title,
+		Notes:     notes,
+		Due:       cloneTimePtr(due),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Revision:  1,
+	}
+	s.applyUnsafe(opAdd, t)
+	return cloneTask(t), nil
+}
+
+// Get returns a copy of the task with the given ID.
+func (s *MemoryStore) Get(id int) (*Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if t := s.index.byID[id]; t != nil {
+		return cloneTask(t), nil
+	}
+	return nil, ErrNotFound
+}
+
+// Update modifies a task.
+func (s *MemoryStore) Update(id int, opts UpdateOptions) (*Task, error) {
+	return s.modify(id, opUpdate, updateTask(opts))
+}
+
+// MarkCompleted sets or clears completion.
+func (s *MemoryStore) MarkCompleted(id int, completed bool) (*Task, error) {
+	return s.modify(id, opComplete, markCompleted(completed))
+}
+
+// ToggleCompleted flips completion state.
+func (s *MemoryStore) ToggleCompleted(id int) (*Task, error) {
+	return s.modify(id, opComplete, toggleCompleted)
+}
+
+// Delete moves a task to the trash.
+func (s *MemoryStore) Delete(id int) error {
+	_, err := s.modify(id, opDelete, deleteTask)
+	return err
+}
+
+// Put stores a fully-formed task as given.
+func (s *MemoryStore) Put(t *Task) (*Task, error) {
+	if t.Title == "" {
+		return nil, ErrTitleRequired
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cp := cloneTask(t)
+	cp.DeletedAt = nil
+	op := opAdd
+	if cur := s.index.byID[t.ID]; cur != nil {
+		op = opUpdate
+		cp.Revision = max(cp.Revision, cur.Revision)
+		ensureUUID(cp, cur)
+	} else if cur := findTask(s.trash, t.ID); cur != nil {
+		cp.Revision = max(cp.Revision, cur.Revision)
+		ensureUUID(cp, cur)
+	}
+	ensureUUID(cp, nil)
+	cp.Revision++
+	s.applyUnsafe(op, cp)
+	return cloneTask(cp), nil
+}
+
+// Import adds fully-formed tasks, keeping their IDs and timestamps.
+func (s *MemoryStore) Import(tasks []*Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range tasks {
+		if s.index.byID[t.ID] != nil || findTask(s.trash, t.ID) != nil {
+			return fmt.Errorf("import task %d: %w", t.ID, ErrExists)
+		}
+	}
+	for _, t := range tasks {
+		cp := cloneTask(t)
+		ensureUUID(cp, nil)
+		if cp.DeletedAt != nil {
+			s.applyUnsafe(opDelete, cp)
+		} else {
+			s.applyUnsafe(opAdd, cp)
+		}
+	}
+	return nil
+}
+
+// ReplaceAll swaps every task in the store for tasks. Trashed tasks stay
+// in the trash unless one of the new tasks takes their ID.
+func (s *MemoryStore) ReplaceAll(tasks []*Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tasks = make([]*Task, 0, len(tasks))
+	for _, t := range tasks {
+		cp := cloneTask(t)
+		ensureUUID(cp, nil)
+		s.tasks = append(s.tasks, cp)
+		s.trash = removeTask(s.trash, t.ID)
+		s.nextID = max(s.nextID, t.ID+1)
+	}
+	s.index = newTaskIndex(s.tasks)
+	return nil
+}
+
+// Trash returns the deleted tasks, most recently deleted first.
+func (s *MemoryStore) Trash() []*Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*Task, len(s.trash))
+	copy(out, s.trash)
+	sortTrash(out)
+	return out
+}
+
+// Restore moves a task out of the trash.
+func (s *MemoryStore) Restore(id int) (*Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur := findTask(s.trash, id)
+	if cur == nil {
+		return nil, ErrNotFound
+	}
+	t := cloneTask(cur)
+	t.DeletedAt = nil
+	t.UpdatedAt = time.Now()
+	recordChanges(cur, t, t.UpdatedAt)
+	t.Revision++
+	s.applyUnsafe(opRestore, t)
+	return cloneTask(t), nil
+}
+
+// Purge removes a task from the trash for good.
+func (s *MemoryStore) Purge(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if findTask(s.trash, id) == nil {
+		return ErrNotFound
+	}
+	s.trash = removeTask(s.trash, id)
+	return nil
+}
+
+// PurgeTrash removes every task deleted at or before the given time.
+func (s *MemoryStore) PurgeTrash(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.trash[:0:0]
+	for _, t := range s.trash {
+		if !expired(t, before) {
+			kept = append(kept, t)
+		}
+	}
+	n := len(s.trash) - len(kept)
+	s.trash = kept
+	return n, nil
+}
+
+func (s *MemoryStore) modify(id int, op string, fn func(t *Task, now time.Time) error) (*Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur := s.index.byID[id]
+	if cur == nil {
+		return nil, ErrNotFound
+	}
+	e, err := changeEntry(cur, op, fn)
+	if err != nil {
+		return nil, err
+	}
+	s.applyUnsafe(e.Op, e.Task)
+	return cloneTask(e.Task), nil
+}
+
+// applyUnsafe stores t, which op changed, and publishes the change. The
+// caller holds the lock.
+func (s *MemoryStore) applyUnsafe(op string, t *Task) {
+	if op == opDelete {
+		s.tasks = removeTask(s.tasks, t.ID)
+		s.index.remove(t.ID)
+		s.trash = upsertTask(s.trash, t)
+	} else {
+		s.trash = removeTask(s.trash, t.ID)
+		s.tasks = upsertTask(s.tasks, t)
+		s.index.put(t)
+	}
+	s.nextID = max(s.nextID, t.ID+1)
+	s.publish(op, t)
+}
+
+// freeID returns the ID the next task added gets.
+func (s *MemoryStore) freeID() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.nextID
+}
+
+// findTask returns the task with the given ID in list, or nil.
+func findTask(list []*Task, id int) *Task {
+	for _, t := range list {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
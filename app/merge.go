@@ -0,0 +1,389 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// A three-way merge combines two copies of the JSON store, ours and theirs,
+// that both descend from base, e.g. the files of two laptops that edited
+// offline. Tasks are matched by ID and merged field by field: a field
+// changed on one side only takes that change, a field changed on both
+// sides to different values is a conflict that the side updated last wins.
+//
+// Both sides derive new IDs from the highest one they know, so tasks added
+// on each side can share an ID; their UUIDs tell them apart. Such a task of
+// theirs is given a fresh ID.
+
+// MergeConflict is a field that both sides changed to different values.
+type MergeConflict struct {
+	ID     int // ID of the task in the merged store
+	Title  string
+	Field  string
+	Ours   string
+	Theirs string
+	Kept   string // "ours" or "theirs"
+}
+
+func (c MergeConflict) String() string {
+	return fmt.Sprintf("#%d %s: %s is %s in ours but %s in theirs; kept %s",
+		c.ID, c.Title, c.Field, c.Ours, c.Theirs, c.Kept)
+}
+
+// Renumbering is a task of theirs that was given a new ID because ours
+// added a different task with the same ID.
+type Renumbering struct {
+	From, To int
+	Title    string
+}
+
+func (r Renumbering) String() string {
+	return fmt.Sprintf("#%d %s is now #%d", r.From, r.Title, r.To)
+}
+
+// MergeReport describes what a merge had to decide.
+type MergeReport struct {
+	Conflicts  []MergeConflict
+	Renumbered []Renumbering
+}
+
+// MergeFiles merges the contents of three store files and returns the
+// merged file. base may be empty when the copies have no common ancestor.
+// Encrypted files need secret; the result is encrypted if ours was.
+func MergeFiles(base, ours, theirs []byte, secret *Secret) ([]byte, MergeReport, error) {
+	var snaps [3]*snapshot
+	for i, data := range [][]byte{base, ours, theirs} {
+		plain, err := unseal(secret, data)
+		if err != nil {
+			return nil, MergeReport{}, fmt.Errorf("open %s: %w", mergeSides[i], err)
+		}
+		if snaps[i], err = decodeSnapshot(plain); err != nil {
+			return nil, MergeReport{}, fmt.Errorf("decode %s: %w", mergeSides[i], err)
+		}
+	}
+
+	merged, report := mergeSnapshots(snaps[0], snaps[1], snaps[2])
+	data, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return nil, report, fmt.Errorf("encode store: %w", err)
+	}
+	if isSealed(ours) {
+		if data, err = sealWith(secret, data); err != nil {
+			return nil, report, fmt.Errorf("encrypt store: %w", err)
+		}
+	}
+	return append(data, '\n'), report, nil
+}
+
+var mergeSides = [3]string{"base", "ours", "theirs"}
+
+// mergeSnapshots merges the tasks and trash of three decoded store files.
+func mergeSnapshots(base, ours, theirs *snapshot) (*snapshot, MergeReport) {
+	var report MergeReport
+	b, o, t := snapshotTasks(base), snapshotTasks(ours), snapshotTasks(theirs)
+
+	ids := map[int]bool{}
+	next := max(1, base.NextID, ours.NextID, theirs.NextID)
+	for _, m := range []map[int]*Task{b, o, t} {
+		for id := range m {
+			ids[id] = true
+			next = max(next, id+1)
+		}
+	}
+	sorted := make([]int, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Ints(sorted)
+
+	var out []*Task
+	for _, id := range sorted {
+		bt, ot, tt := b[id], o[id], t[id]
+		if bt == nil && ot != nil && tt != nil && ot.UUID != tt.UUID {
+			// Added on both sides under the same ID.
+			moved := cloneTask(tt)
+			moved.ID = next
+			next++
+			report.Renumbered = append(report.Renumbered, Renumbering{From: id, To: moved.ID, Title: moved.Title})
+			out = append(out, ot, moved)
+			continue
+		}
+		if merged := mergeTask(bt, ot, tt, &report); merged != nil {
+			out = append(out, merged)
+		}
+	}
+
+	merged := &snapshot{
+		Version: max(base.Version, ours.Version, theirs.Version, CurrentVersion),
+		Tasks:   []*Task{},
+		NextID:  next,
+		extra:   ours.extra,
+	}
+	for _, task := range out {
+		if task.DeletedAt != nil {
+			merged.Trash = append(merged.Trash, task)
+		} else {
+			merged.Tasks = append(merged.Tasks, task)
+		}
+	}
+	sort.SliceStable(merged.Tasks, func(i, j int) bool { return merged.Tasks[i].ID < merged.Tasks[j].ID })
+	sortTrash(merged.Trash)
+	return merged, report
+}
+
+// snapshotTasks indexes the live and trashed tasks of a store file by ID.
+func snapshotTasks(s *snapshot) map[int]*Task {
+	m := make(map[int]*Task, len(s.Tasks)+len(s.Trash))
+	for _, t := range s.Tasks {
+		m[t.ID] = t
+	}
+	for _, t := range s.Trash {
+		m[t.ID] = t
+	}
+	return m
+}
+
+// mergeTask merges one task. A nil task is absent on that side: never
+// added, or purged from the trash. It returns nil if the task is gone.
+func mergeTask(base, ours, theirs *Task, report *MergeReport) *Task {
+	switch {
+	case ours == nil && theirs == nil:
+		return nil
+	case ours == nil:
+		return mergePurged(base, theirs, "theirs", report)
+	case theirs == nil:
+		return mergePurged(base, ours, "ours", report)
+	case sameFields(ours, theirs):
+		if theirs.Revision > ours.Revision {
+			return theirs
+		}
+		return ours
+	}
+
+	oursWins := !theirs.UpdatedAt.After(ours.UpdatedAt)
+	merged := cloneTask(ours)
+	var conflicts []MergeConflict
+	for _, f := range taskFields {
+		ov, tv := f.get(ours), f.get(theirs)
+		if f.equal(ov, tv) {
+			continue
+		}
+		switch {
+		case base != nil && f.equal(f.get(base), ov):
+			f.set(merged, tv)
+		case base != nil && f.equal(f.get(base), tv):
+			// Ours already holds the change.
+		case f.resolve != nil && f.resolve(merged, ov, tv):
+			// Both sides made the same kind of change, e.g. completed the task.
+		default:
+			c := MergeConflict{ID: ours.ID, Field: f.name, Ours: f.show(ov), Theirs: f.show(tv), Kept: "ours"}
+			if !oursWins {
+				f.set(merged, tv)
+				c.Kept = "theirs"
+			}
+			conflicts = append(conflicts, c)
+		}
+	}
+	for _, c := range conflicts {
+		c.Title = merged.Title
+		report.Conflicts = append(report.Conflicts, c)
+	}
+
+	if theirs.UpdatedAt.After(merged.UpdatedAt) {
+		merged.UpdatedAt = theirs.UpdatedAt
+	}
+	merged.Revision = max(ours.Revision, theirs.Revision) + 1
+	return merged
+}
+
+// mergePurged decides between a task purged on one side and its copy on
+// the other: the purge wins unless the copy was changed since base in any
+// other way than being deleted.
+func mergePurged(base, kept *Task, side string, report *MergeReport) *Task {
+	if base == nil {
+		return kept // added on one side only
+	}
+	changed := false
+	for _, f := range taskFields {
+		if f.name != "deleted" && f.name != "history" && !f.equal(f.get(base), f.get(kept)) {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	c := MergeConflict{ID: kept.ID, Title: kept.Title, Field: "task", Kept: side}
+	if side == "ours" {
+		c.Ours, c.Theirs = "edited", "purged"
+	} else {
+		c.Ours, c.Theirs = "purged", "edited"
+	}
+	report.Conflicts = append(report.Conflicts, c)
+	return kept
+}
+
+// sameFields reports whether two copies of a task agree on every merged field.
+func sameFields(a, b *Task) bool {
+	for _, f := range taskFields {
+		if !f.equal(f.get(a), f.get(b)) {
+			return false
+		}
+	}
+	return true
+}
+
+// taskField is a field of Task that is merged on its own.
+type taskField struct {
+	name  string
+	get   func(t *Task) any
+	set   func(t *Task, v any)
+	equal func(a, b any) bool
+	show  func(v any) string
+	// resolve merges two different values without a conflict, if it can.
+	resolve func(t *Task, ours, theirs any) bool
+	// recorded fields are kept in the task's history when they change.
+	recorded bool
+}
+
+var taskFields = []taskField{
+	{
+		name:     "title",
+		get:      func(t *Task) any { return t.Title },
+		set:      func(t *Task, v any) { t.Title = v.(string) },
+		equal:    func(a, b any) bool { return a == b },
+		show:     func(v any) string { return fmt.Sprintf("%q", v) },
+		recorded: true,
+	},
+	{
+		name:     "notes",
+		get:      func(t *Task) any { return t.Notes },
+		set:      func(t *Task, v any) { t.Notes = v.(string) },
+		equal:    func(a, b any) bool { return a == b },
+		show:     func(v any) string { return fmt.Sprintf("%q", v) },
+		recorded: true,
+	},
+	{
+		name:     "tags",
+		get:      func(t *Task) any { return t.Tags },
+		set:      func(t *Task, v any) { t.Tags = v.([]string) },
+		equal:    equalTags,
+		show:     showTags,
+		recorded: true,
+	},
+	{
+		name:     "priority",
+		get:      func(t *Task) any { return t.Priority },
+		set:      func(t *Task, v any) { t.Priority = v.(Priority) },
+		equal:    func(a, b any) bool { return a == b },
+		show:     func(v any) string { return v.(Priority).String() },
+		recorded: true,
+	},
+	{
+		name:     "project",
+		get:      func(t *Task) any { return t.Project },
+		set:      func(t *Task, v any) { t.Project = v.(string) },
+		equal:    func(a, b any) bool { return a == b },
+		show:     showProject,
+		recorded: true,
+	},
+	{
+		name:     "day",
+		get:      func(t *Task) any { return t.Date },
+		set:      func(t *Task, v any) { t.Date = v.(time.Time) },
+		equal:    func(a, b any) bool { return a.(time.Time).Equal(b.(time.Time)) },
+		show:     func(v any) string { return v.(time.Time).Format("2006-01-02") },
+		recorded: true,
+	},
+	{
+		name:     "due",
+		get:      func(t *Task) any { return t.Due },
+		set:      func(t *Task, v any) { t.Due = cloneTimePtr(v.(*time.Time)) },
+		equal:    equalTimes,
+		show:     showTime,
+		recorded: true,
+	},
+	{
+		name:     "completed",
+		get:      func(t *Task) any { return t.CompletedAt },
+		set:      func(t *Task, v any) { t.CompletedAt = cloneTimePtr(v.(*time.Time)) },
+		equal:    equalTimes,
+		show:     showTime,
+		resolve:  earliest(func(t *Task, v *time.Time) { t.CompletedAt = v }),
+		recorded: true,
+	},
+	{
+		name:     "deleted",
+		get:      func(t *Task) any { return t.DeletedAt },
+		set:      func(t *Task, v any) { t.DeletedAt = cloneTimePtr(v.(*time.Time)) },
+		equal:    equalTimes,
+		show:     showTime,
+		resolve:  earliest(func(t *Task, v *time.Time) { t.DeletedAt = v }),
+		recorded: true,
+	},
+	{
+		name:  "history",
+		get:   func(t *Task) any { return t.Changes },
+		set:   func(t *Task, v any) { t.Changes = v.([]TaskChange) },
+		equal: equalChanges,
+		show:  func(v any) string { return fmt.Sprintf("%d changes", len(v.([]TaskChange))) },
+		resolve: func(t *Task, ours, theirs any) bool {
+			t.Changes = mergeChanges(ours.([]TaskChange), theirs.([]TaskChange))
+			return true
+		},
+	},
+	{
+		name:  "extra fields",
+		get:   func(t *Task) any { return t.Extra },
+		set:   func(t *Task, v any) { t.Extra = v.(map[string]json.RawMessage) },
+		equal: equalExtra,
+		show:  func(v any) string { return fmt.Sprintf("%d fields", len(v.(map[string]json.RawMessage))) },
+	},
+}
+
+// earliest resolves a timestamp set on both sides at different times, e.g.
+// a task completed on both laptops, by keeping the earlier one.
+func earliest(set func(t *Task, v *time.Time)) func(t *Task, ours, theirs any) bool {
+	return func(t *Task, ours, theirs any) bool {
+		o, th := ours.(*time.Time), theirs.(*time.Time)
+		if o == nil || th == nil {
+			return false
+		}
+		if th.Before(*o) {
+			o = th
+		}
+		set(t, cloneTimePtr(o))
+		return true
+	}
+}
+
+func equalTimes(a, b any) bool {
+	x, y := a.(*time.Time), b.(*time.Time)
+	if x == nil || y == nil {
+		return x == y
+	}
+	return x.Equal(*y)
+}
+
+func showTime(v any) string {
+	if t := v.(*time.Time); t != nil {
+		return t.Format("2006-01-02 15:04")
+	}
+	return "none"
+}
+
+func equalExtra(a, b any) bool {
+	x, y := a.(map[string]json.RawMessage), b.(map[string]json.RawMessage)
+	if len(x) != len(y) {
+		return false
+	}
+	for k, v := range x {
+		w, ok := y[k]
+		if !ok || !bytes.Equal(v, w) {
+			return false
+		}
+	}
+	return true
+}
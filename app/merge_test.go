@@ -0,0 +1,130 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	at := func(h int) *time.Time {
+		v := day.Add(time.Duration(h) * time.Hour)
+		return &v
+	}
+	task := func(id int, title string, updated int) *Task {
+		return &Task{ID: id, UUID: newUUID(), Date: day, Title: title, CreatedAt: *at(updated), UpdatedAt: *at(updated), Revision: 1}
+	}
+	edit := func(t *Task, fn func(t *Task)) *Task {
+		t = cloneTask(t)
+		fn(t)
+		t.Revision++
+		return t
+	}
+
+	report := task(1, "Write report", 1)
+	call := task(2, "Call ACME", 2)
+	old := &Task{ID: 3, UUID: newUUID(), Date: day, Title: "Old", CreatedAt: *at(3), UpdatedAt: *at(3), DeletedAt: at(3), Revision: 2}
+	base := &snapshot{Version: CurrentVersion, Tasks: []*Task{report, call}, Trash: []*Task{old}}
+
+	ours := &snapshot{Version: CurrentVersion, Tasks: []*Task{
+		edit(report, func(t *Task) { t.Title = "Write annual report"; t.UpdatedAt = *at(10) }),
+		edit(call, func(t *Task) { t.Notes = "ask about the invoice"; t.UpdatedAt = *at(10) }),
+		task(4, "Ours new", 10),
+	}, Trash: []*Task{
+		edit(old, func(t *Task) { t.Notes = "why?"; t.UpdatedAt = *at(10) }),
+	}}
+	theirs := &snapshot{Version: CurrentVersion, Tasks: []*Task{
+		edit(report, func(t *Task) { t.CompletedAt = at(11); t.UpdatedAt = *at(11) }),
+		task(4, "Theirs new", 11),
+		task(5, "Theirs newer", 12),
+	}, Trash: []*Task{
+		edit(call, func(t *Task) { t.Notes = "never mind"; t.DeletedAt = at(12); t.UpdatedAt = *at(12) }),
+	}}
+	// Task 3 was purged from theirs' trash.
+
+	merged, got := mergeSnapshots(base, ours, theirs)
+	tasks := snapshotTasks(merged)
+
+	if r := tasks[1]; r.Title != "Write annual report" || !r.IsCompleted() || r.Revision != 3 {
+		t.Errorf("task 1 = %+v, want both edits", r)
+	}
+	if c := tasks[2]; c.DeletedAt == nil || c.Notes != "never mind" {
+		t.Errorf("task 2 = %+v, want deleted with theirs' newer notes", c)
+	}
+	if o := tasks[3]; o == nil || o.Notes != "why?" {
+		t.Errorf("task 3 = %+v, want ours' edit kept over theirs' purge", o)
+	}
+	if tasks[4].Title != "Ours new" || tasks[6].Title != "Theirs new" || tasks[5].Title != "Theirs newer" {
+		t.Errorf("tasks 4-6 = %q, %q, %q", tasks[4].Title, tasks[5].Title, tasks[6].Title)
+	}
+	if len(got.Renumbered) != 1 || got.Renumbered[0] != (Renumbering{From: 4, To: 6, Title: "Theirs new"}) {
+		t.Errorf("Renumbered = %v", got.Renumbered)
+	}
+	if len(got.Conflicts) != 2 {
+		t.Fatalf("Conflicts = %v, want notes of #2 and the purge of #3", got.Conflicts)
+	}
+	if c := got.Conflicts[0]; c.ID != 2 || c.Field != "notes" || c.Kept != "theirs" {
+		t.Errorf("Conflicts[0] = %v", c)
+	}
+	if c := got.Conflicts[1]; c.ID != 3 || c.Field != "task" || c.Kept != "ours" {
+		t.Errorf("Conflicts[1] = %v", c)
+	}
+	if len(merged.Tasks) != 4 || len(merged.Trash) != 2 {
+		t.Errorf("merged has %d tasks and %d trashed, want 4 and 2", len(merged.Tasks), len(merged.Trash))
+	}
+}
+
+func TestMergeCompletedTwice(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	early, late := day.Add(time.Hour), day.Add(2*time.Hour)
+	base := &Task{ID: 1, Date: day, Title: "Pay rent", CreatedAt: day, UpdatedAt: day, Revision: 1}
+	ours, theirs := cloneTask(base), cloneTask(base)
+	ours.CompletedAt, ours.UpdatedAt = &late, late
+	theirs.CompletedAt, theirs.UpdatedAt = &early, early
+
+	var report MergeReport
+	merged := mergeTask(base, ours, theirs, &report)
+	if !merged.CompletedAt.Equal(early) || len(report.Conflicts) != 0 {
+		t.Errorf("CompletedAt = %v, conflicts %v, want the earlier completion without conflict", merged.CompletedAt, report.Conflicts)
+	}
+}
+
+func TestMergeFiles(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	encode := func(tasks ...*Task) []byte {
+		data, err := json.Marshal(snapshot{Version: CurrentVersion, Tasks: tasks})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	secret := NewSecret([]byte("correct horse"))
+	ours, err := secret.seal(encode(&Task{ID: 1, Date: day, Title: "ours", CreatedAt: day, UpdatedAt: day, Revision: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	theirs := encode(&Task{ID: 1, Date: day, Title: "theirs", CreatedAt: day.Add(time.Hour), UpdatedAt: day, Revision: 1})
+
+	if _, _, err := MergeFiles(nil, ours, theirs, nil); err == nil {
+		t.Fatalf("MergeFiles() of an encrypted file without secret succeeded")
+	}
+	data, report, err := MergeFiles(nil, ours, theirs, secret)
+	if err != nil {
+		t.Fatalf("MergeFiles() error = %v", err)
+	}
+	if !isSealed(data) {
+		t.Errorf("merge of an encrypted file is not encrypted")
+	}
+	plain, err := secret.open(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := decodeSnapshot(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Tasks) != 2 || len(report.Renumbered) != 1 {
+		t.Errorf("merged %d tasks, renumbered %v, want 2 tasks and one renumbering", len(snap.Tasks), report.Renumbered)
+	}
+}
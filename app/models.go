@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -12,18 +11,28 @@ import (
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
 )
 
 type TaskFormResultMsg struct {
-	Result bool
-	Title  string
-	Notes  string
+	Result   bool
+	Title    string
+	Notes    string
+	Tags     []string
+	Priority Priority
+	Project  string
 }
 
 type DaySelectedMsg struct {
 	Day time.Time
 }
 
+// ProjectSelectedMsg scopes the task list to the project with Path and its
+// subprojects, across all days. An empty Path shows the day again.
+type ProjectSelectedMsg struct {
+	Path string
+}
+
 type DeleteConfirmationMsg struct {
 	Confirmed bool
 	TaskID    int
@@ -33,16 +42,75 @@ type DeleteRequestMsg struct {
 	TaskID int
 }
 
+// StoreChangedMsg is sent when the task store was changed by another
+// process and has been reloaded.
+type StoreChangedMsg struct{}
+
+// StatusMsg carries a short notice for the footer, e.g. what was undone.
+type StatusMsg struct {
+	Text string
+}
+
+// Status returns a command that shows text in the footer.
+func Status(text string) tea.Cmd {
+	return func() tea.Msg {
+		return StatusMsg{Text: text}
+	}
+}
+
+// ListSelectedMsg asks to switch to the named task list.
+type ListSelectedMsg struct {
+	Name string
+}
+
+// ListSwitchedMsg is sent to the panes once the named task list is open;
+// they show Store from then on.
+type ListSwitchedMsg struct {
+	Name  string
+	Store TaskStore
+}
+
 // Task represents a single to-do item.
 type Task struct {
-	ID          int        `json:"id"`
+	ID          int        `json:"id"`   // short handle, unique within the store
+	UUID        string     `json:"uuid"` // identity across stores, see uuid.go
 	Date        time.Time  `json:"date"`
 	Title       string     `json:"title"`
 	Notes       string     `json:"notes,omitempty"`
+	Tags        []string   `json:"tags,omitempty"` // see tags.go
+	Priority    Priority   `json:"priority,omitempty"`
+	Project     string     `json:"project,omitempty"` // a dotted path, see projects.go
 	Due         *time.Time `json:"due,omitempty"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"` // set while the task is in the trash
+	Revision    int        `json:"revision"`
+	// Changes is the history of the task, oldest first. It only grows by
+	// copying, so clones of a task can share it.
+	Changes []TaskChange `json:"changes,omitempty"`
+
+	// Extra holds fields written by newer versions of Taskman; they are
+	// preserved when the task is saved again.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+func (t *Task) UnmarshalJSON(data []byte) error {
+	type plain Task
+	extra, err := unmarshalKnown(data, (*plain)(t))
+	if err != nil {
+		return err
+	}
+	t.Extra = extra
+	if t.UUID == "" {
+		t.UUID = legacyUUID(t.ID, t.CreatedAt)
+	}
+	return nil
+}
+
+func (t Task) MarshalJSON() ([]byte, error) {
+	type plain Task
+	return marshalWithExtra(plain(t), t.Extra)
 }
 
 // IsCompleted returns true if the task is completed.
@@ -50,189 +118,241 @@ func (t *Task) IsCompleted() bool {
 	return t.CompletedAt != nil
 }
 
-// Store manages tasks persisted to a JSON file.
+// Store manages tasks persisted to a JSON snapshot plus an append-only
+// journal of operations (see journal.go). Mutations only append to the
+// journal; it is folded into the snapshot every CompactEvery operations.
 // It is safe for concurrent use.
+//
+// Other processes may share the same files: every mutation takes an
+// advisory lock (see lock.go), catches up with their changes and fails
+// with ErrConflict instead of overwriting a task it has a stale copy of.
 type Store struct {
-	mu     sync.RWMutex
-	path   string
-	tasks  []*Task
-	NextID int
+	mu      sync.RWMutex
+	path    string
+	tasks   []*Task
+	index   *taskIndex // of tasks, see index.go
+	trash   []*Task    // deleted tasks, until restored or purged
+	NextID  int
+	idFloor int // IDs below it are taken, also by archived tasks
+	opts    options
+	journal *os.File
+	pending int // journal entries not yet compacted into the snapshot
+	lockf   *os.File
+	disk    diskState
+	watcher *fsnotify.Watcher
+	version int                        // schema version of the file on disk
+	extra   map[string]json.RawMessage // unknown top-level fields, kept on save
+	auto    *autoBackup
+	git     *gitRepo        // set for git-backed stores
+	archive map[int][]*Task // archive files read so far, by year
+
+	subscribers
 }
 
 // Errors returned by Store operations.
 var (
 	ErrNotFound      = errors.New("task not found")
 	ErrTitleRequired = errors.New("title is required")
+	ErrExists        = errors.New("task already exists")
+	ErrConflict      = errors.New("task was changed by another process")
 )
 
 // Load opens (or initializes) a task store backed by the given JSON file.
 // If the file does not exist, an empty store is created on first Save.
-func Load(path string) (*Store, error) {
-	s := &Store{path: path, tasks: []*Task{}, NextID: 1}
-
-	f, err := os.Open(path)
-	if err != nil {
-		// If file doesn't exist yet, that's fine—start empty.
-		if errors.Is(err, os.ErrNotExist) {
-			return s, nil
+// Operations journaled since the last compaction are replayed on top of it.
+func Load(path string, opts ...Option) (*Store, error) {
+	s := &Store{path: path, tasks: []*Task{}, index: newTaskIndex(nil), trash: []*Task{}, NextID: 1, opts: newOptions(opts)}
+	s.auto = s.opts.autoBackup(path)
+	if s.opts.git {
+		g, err := openGitRepo(filepath.Dir(path))
+		if err != nil {
+			return nil, err
 		}
-		return nil, fmt.Errorf("open store: %w", err)
+		s.git = g
 	}
-	defer f.Close()
 
-	dec := json.NewDecoder(f)
-	dec.DisallowUnknownFields()
+	if err := s.lockUnsafe(); err != nil {
+		return nil, err
+	}
+	defer s.unlockUnsafe()
 
-	var onDisk struct {
-		Tasks []*Task `json:"tasks"`
+	if _, err := s.syncUnsafe(); err != nil {
+		return nil, err
 	}
-	if err := dec.Decode(&onDisk); err != nil && !errors.Is(err, io.EOF) {
-		return nil, fmt.Errorf("decode store: %w", err)
+	if s.opts.trashRetention > 0 {
+		if _, err := s.purgeTrashUnsafe(time.Now().Add(-s.opts.trashRetention)); err != nil {
+			return nil, err
+		}
 	}
-	s.tasks = onDisk.Tasks
-	// Determine nextID from max existing ID.
-	maxID := 0
-	for _, t := range s.tasks {
-		if t.ID > maxID {
-			maxID = t.ID
+	if s.opts.archiveAfter > 0 {
+		if _, err := s.archiveUnsafe(time.Now().Add(-s.opts.archiveAfter)); err != nil {
+			return nil, err
+		}
+	}
+	if s.needsCompactUnsafe() {
+		if err := s.compactUnsafe(); err != nil {
+			return nil, err
 		}
 	}
-	s.NextID = maxID + 1
 	return s, nil
 }
 
-// Save writes the current tasks to disk atomically.
+// Save writes the current tasks to the snapshot atomically and empties
+// the journal.
 func (s *Store) Save() error {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	dir := filepath.Dir(s.path)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return fmt.Errorf("mkdir store dir: %w", err)
+	if err := s.beginUnsafe(); err != nil {
+		return err
+	}
+	defer s.unlockUnsafe()
+	if err := s.compactUnsafe(); err != nil {
+		return err
 	}
+	return s.gitCommitUnsafe("save tasks")
+}
 
-	tmp, err := os.CreateTemp(dir, ".tasks-*.tmp")
-	if err != nil {
-		return fmt.Errorf("create temp: %w", err)
+// Reload discards the in-memory tasks and reads the store from disk again.
+func (s *Store) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.disk = diskState{}
+	if err := s.beginUnsafe(); err != nil {
+		return err
 	}
-	tmpPath := tmp.Name()
+	s.unlockUnsafe()
+	return nil
+}
 
-	enc := json.NewEncoder(tmp)
-	enc.SetIndent("", "  ")
-	payload := struct {
-		Tasks []*Task `json:"tasks"`
-	}{Tasks: s.tasks}
+// Rekey rewrites the store encrypted with secret, or in plain JSON when
+// secret is nil, and uses it for every later write.
+func (s *Store) Rekey(secret *Secret) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	if err := enc.Encode(payload); err != nil {
-		tmp.Close()
-		os.Remove(tmpPath)
-		return fmt.Errorf("encode store: %w", err)
+	if err := s.beginUnsafe(); err != nil {
+		return err
 	}
-	// Ensure data hits disk before rename.
-	if err := tmp.Sync(); err != nil {
-		tmp.Close()
-		os.Remove(tmpPath)
-		return fmt.Errorf("sync temp: %w", err)
+	defer s.unlockUnsafe()
+
+	// Read the archive with the old secret before writing it with the new one.
+	years, err := s.archiveYearsUnsafe()
+	if err != nil {
+		return err
 	}
-	if err := tmp.Close(); err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("close temp: %w", err)
+	for _, year := range years {
+		if _, err := s.readArchiveUnsafe(year); err != nil {
+			return err
+		}
 	}
-	// Atomic replace.
-	if err := os.Rename(tmpPath, s.path); err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("rename store: %w", err)
+
+	s.opts.secret = secret
+	if s.auto != nil {
+		s.auto.backups.Secret = secret
 	}
-	// Best effort directory sync (ignore errors on some OSes).
-	if d, err := os.Open(dir); err == nil {
-		_ = d.Sync()
-		_ = d.Close()
+	if err := s.compactUnsafe(); err != nil {
+		return err
 	}
-	return nil
+	for _, year := range years {
+		if err := s.writeArchiveUnsafe(year, s.archive[year]); err != nil {
+			return err
+		}
+	}
+	if secret == nil {
+		return s.gitCommitUnsafe("decrypt tasks")
+	}
+	return s.gitCommitUnsafe("encrypt tasks")
+}
+
+// Close compacts any pending journal entries and releases the journal file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	if s.watcher != nil {
+		err = s.watcher.Close()
+		s.watcher = nil
+	}
+	if s.pending > 0 {
+		if err = s.beginUnsafe(); err == nil {
+			err = s.compactUnsafe()
+			s.unlockUnsafe()
+		}
+	}
+	for _, f := range []**os.File{&s.journal, &s.lockf} {
+		if *f != nil {
+			if cerr := (*f).Close(); err == nil {
+				err = cerr
+			}
+			*f = nil
+		}
+	}
+	return err
 }
 
 // List returns a copy of tasks, sorted by:
-// 1) incomplete first by due date (nil due goes last),
-// 2) then completed by completion time,
+// 1) incomplete first by priority, then due date (tasks without one last),
+// 2) then completed by completion time, newest first,
 // 3) finally by ID for stability.
 func (s *Store) List() []*Task {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	return s.listUnsafe()
+}
 
+func (s *Store) listUnsafe() []*Task {
 	out := make([]*Task, len(s.tasks))
 	copy(out, s.tasks)
 
-	sort.SliceStable(out, func(i, j int) bool {
-		a, b := out[i], out[j]
-
-		// Incomplete before complete
-		if a.IsCompleted() != b.IsCompleted() {
-			return !a.IsCompleted()
-		}
-
-		// If both incomplete: sort by due date (nil last), then ID
-		if !a.IsCompleted() {
-			if a.Due == nil && b.Due != nil {
-				return false
-			}
-			if a.Due != nil && b.Due == nil {
-				return true
-			}
-			if a.Due != nil && b.Due != nil && !a.Due.Equal(*b.Due) {
-				return a.Due.Before(*b.Due)
-			}
-			return a.ID < b.ID
-		}
-
-		// If both complete: newest completion first
-		if a.CompletedAt != nil && b.CompletedAt != nil && !a.CompletedAt.Equal(*b.CompletedAt) {
-			return b.CompletedAt.Before(*a.CompletedAt)
-		}
-		return a.ID < b.ID
-	})
+	sortTasks(out)
 	return out
 }
 
+// ListByDate returns the tasks scheduled for the given day, sorted like List.
 func (s *Store) ListByDate(date time.Time) []*Task {
+	return s.ListRange(date, date)
+}
+
+// ListRange returns the tasks scheduled from the day of from through the
+// day of to, by day and then sorted like List.
+func (s *Store) ListRange(from, to time.Time) []*Task {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	return s.index.between(from, to)
+}
 
-	var out []*Task
-	for _, t := range s.tasks {
-		if t.Date.Year() == date.Year() && t.Date.Month() == date.Month() && t.Date.Day() == date.Day() {
-			out = append(out, t)
-		}
-	}
-
-	sort.SliceStable(out, func(i, j int) bool {
-		a, b := out[i], out[j]
+// Overdue returns the open tasks scheduled before the day of now, by
+// priority, then due date (tasks without one last), then by ID.
+func (s *Store) Overdue(now time.Time) []*Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index.overdue(now)
+}
 
-		// Incomplete before complete
-		if a.IsCompleted() != b.IsCompleted() {
-			return !a.IsCompleted()
-		}
+// ListOpen returns the open tasks, sorted like Overdue.
+func (s *Store) ListOpen() []*Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index.listOpen()
+}
 
-		// If both incomplete: sort by due date (nil last), then ID
-		if !a.IsCompleted() {
-			if a.Due == nil && b.Due != nil {
-				return false
-			}
-			if a.Due != nil && b.Due == nil {
-				return true
-			}
-			if a.Due != nil && b.Due != nil && !a.Due.Equal(*b.Due) {
-				return a.Due.Before(*b.Due)
-			}
-			return a.ID < b.ID
-		}
+// CompletedOn returns the tasks completed on the day of day, sorted like
+// List.
+func (s *Store) CompletedOn(day time.Time) []*Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index.completedOn(day)
+}
 
-		// If both complete: newest completion first
-		if a.CompletedAt != nil && b.CompletedAt != nil && !a.CompletedAt.Equal(*b.CompletedAt) {
-			return b.CompletedAt.Before(*a.CompletedAt)
-		}
-		return a.ID < b.ID
-	})
-	return out
+// ListProject returns the tasks in the project with the given path or one
+// below it, or in any project if path is empty, sorted like List.
+func (s *Store) ListProject(path string) []*Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index.inProject(path)
 }
 
 // Add creates a new task and saves it to disk.
@@ -241,38 +361,49 @@ func (s *Store) Add(title, notes string, due *time.Time, date time.Time) (*Task,
 		return nil, ErrTitleRequired
 	}
 
-	now := time.Now()
 	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.beginUnsafe(); err != nil {
+		return nil, err
+	}
+	defer s.unlockUnsafe()
+
+	e := s.addEntryUnsafe(title, notes, due, date)
+	if err := s.commitUnsafe(e); err != nil {
+		return nil, err
+	}
+	return cloneTask(e.Task), nil
+}
+
+// addEntryUnsafe returns the journal entry that adds a new task under the
+// next free ID.
+func (s *Store) addEntryUnsafe(title, notes string, due *time.Time, date time.Time) journalEntry {
+	now := time.Now()
 	t := &Task{
 		ID:        s.NextID,
+		UUID:      newUUID(),
 		Date:      date,
 		Title:     title,
 		Notes:     notes,
 		Due:       cloneTimePtr(due),
 		CreatedAt: now,
 		UpdatedAt: now,
+		Revision:  1,
 	}
-	s.tasks = append(s.tasks, t)
-	s.NextID++
-	s.mu.Unlock()
-
-	if err := s.Save(); err != nil {
-		return nil, err
-	}
-	return t, nil
+	return journalEntry{Op: opAdd, At: now, Task: t}
 }
 
 // Get returns a copy of the task with the given ID.
 func (s *Store) Get(id int) (*Task, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	for _, t := range s.tasks {
-		if t.ID == id {
-			cp := *t
-			cp.Due = cloneTimePtr(t.Due)
-			cp.CompletedAt = cloneTimePtr(t.CompletedAt)
-			return &cp, nil
-		}
+	return s.getUnsafe(id)
+}
+
+func (s *Store) getUnsafe(id int) (*Task, error) {
+	if t := s.findUnsafe(id); t != nil {
+		return cloneTask(t), nil
 	}
 	return nil, ErrNotFound
 }
@@ -280,144 +411,347 @@ func (s *Store) Get(id int) (*Task, error) {
 // UpdateOptions defines which fields to change.
 // Use pointer fields so "nil" means "leave unchanged".
 type UpdateOptions struct {
-	Title *string
-	Notes *string
-	Due   **time.Time // pointer to a *time.Time: nil=leave, &nil=clear, &time=update
+	Title    *string
+	Notes    *string
+	Due      **time.Time // pointer to a *time.Time: nil=leave, &nil=clear, &time=update
+	Date     *time.Time  // the day the task is scheduled for
+	Tags     *[]string   // replaces the tags; normalized, see NormalizeTags
+	Priority *Priority
+	Project  *string // moves the task to the project; "" takes it out of any
 }
 
 // Update modifies a task and saves it.
 func (s *Store) Update(id int, opts UpdateOptions) (*Task, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	return s.modify(id, opUpdate, updateTask(opts))
+}
 
-	t := s.findUnsafe(id)
-	if t == nil {
-		return nil, ErrNotFound
-	}
+// MarkCompleted sets or clears completion and saves it.
+func (s *Store) MarkCompleted(id int, completed bool) (*Task, error) {
+	return s.modify(id, opComplete, markCompleted(completed))
+}
+
+// ToggleCompleted flips completion state and saves it.
+func (s *Store) ToggleCompleted(id int) (*Task, error) {
+	return s.modify(id, opComplete, toggleCompleted)
+}
 
-	if opts.Title != nil {
-		if *opts.Title == "" {
-			return nil, ErrTitleRequired
+// Delete moves a task to the trash and saves it.
+func (s *Store) Delete(id int) error {
+	_, err := s.modify(id, opDelete, deleteTask)
+	return err
+}
+
+// The changes made by Update, MarkCompleted, ToggleCompleted and Delete,
+// shared by the stores and Store transactions.
+
+func updateTask(opts UpdateOptions) func(t *Task, now time.Time) error {
+	return func(t *Task, now time.Time) error {
+		if opts.Title != nil {
+			if *opts.Title == "" {
+				return ErrTitleRequired
+			}
+			t.Title = *opts.Title
 		}
-		t.Title = *opts.Title
-	}
-	if opts.Notes != nil {
-		t.Notes = *opts.Notes
+		if opts.Notes != nil {
+			t.Notes = *opts.Notes
+		}
+		if opts.Due != nil {
+			// *opts.Due may be nil (clear) or &time (set)
+			if *opts.Due == nil {
+				t.Due = nil
+			} else {
+				t.Due = cloneTimePtr(*opts.Due)
+			}
+		}
+		if opts.Date != nil {
+			t.Date = *opts.Date
+		}
+		if opts.Tags != nil {
+			t.Tags = NormalizeTags(*opts.Tags)
+		}
+		if opts.Priority != nil {
+			if !opts.Priority.Valid() {
+				return fmt.Errorf("invalid priority %d", int(*opts.Priority))
+			}
+			t.Priority = *opts.Priority
+		}
+		if opts.Project != nil {
+			t.Project = NormalizeProject(*opts.Project)
+		}
+		return nil
 	}
-	if opts.Due != nil {
-		// *opts.Due may be nil (clear) or &time (set)
-		if *opts.Due == nil {
-			t.Due = nil
+}
+
+func markCompleted(completed bool) func(t *Task, now time.Time) error {
+	return func(t *Task, now time.Time) error {
+		if completed {
+			if t.CompletedAt == nil {
+				t.CompletedAt = &now
+			}
 		} else {
-			t.Due = cloneTimePtr(*opts.Due)
+			t.CompletedAt = nil
 		}
+		return nil
+	}
+}
+
+func toggleCompleted(t *Task, now time.Time) error {
+	if t.CompletedAt == nil {
+		t.CompletedAt = &now
+	} else {
+		t.CompletedAt = nil
+	}
+	return nil
+}
+
+func deleteTask(t *Task, now time.Time) error {
+	t.DeletedAt = &now
+	return nil
+}
+
+// Put stores a fully-formed task as given and saves it.
+func (s *Store) Put(t *Task) (*Task, error) {
+	if t.Title == "" {
+		return nil, ErrTitleRequired
 	}
-	t.UpdatedAt = time.Now()
 
-	// Persist outside the lock boundary to reduce contention,
-	// but we already mutated; to keep simple, save while still holding lock.
-	// (If your workload is heavy, copy tasks and save without lock.)
-	if err := s.saveUnsafe(); err != nil {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.beginUnsafe(); err != nil {
 		return nil, err
 	}
-	cp := *t
-	cp.Due = cloneTimePtr(t.Due)
-	cp.CompletedAt = cloneTimePtr(t.CompletedAt)
-	return &cp, nil
+	defer s.unlockUnsafe()
+
+	e := s.putEntryUnsafe(t)
+	if err := s.commitUnsafe(e); err != nil {
+		return nil, err
+	}
+	return cloneTask(e.Task), nil
 }
 
-// MarkCompleted sets or clears completion and saves it.
-func (s *Store) MarkCompleted(id int, completed bool) (*Task, error) {
+// putEntryUnsafe returns the journal entry that stores t as given, with
+// its revision bumped past the one it replaces.
+func (s *Store) putEntryUnsafe(t *Task) journalEntry {
+	cp := cloneTask(t)
+	cp.DeletedAt = nil
+	op := opAdd
+	if cur := s.findUnsafe(t.ID); cur != nil {
+		op = opUpdate
+		cp.Revision = max(cp.Revision, cur.Revision)
+		ensureUUID(cp, cur)
+	} else if cur := s.findTrashUnsafe(t.ID); cur != nil {
+		cp.Revision = max(cp.Revision, cur.Revision)
+		ensureUUID(cp, cur)
+	}
+	ensureUUID(cp, nil)
+	cp.Revision++
+	return journalEntry{Op: op, At: time.Now(), Task: cp}
+}
+
+// Import adds fully-formed tasks, keeping their IDs and timestamps,
+// and saves once.
+func (s *Store) Import(tasks []*Task) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	t := s.findUnsafe(id)
-	if t == nil {
-		return nil, ErrNotFound
+	if err := s.beginUnsafe(); err != nil {
+		return err
 	}
+	defer s.unlockUnsafe()
 
 	now := time.Now()
-	if completed {
-		if t.CompletedAt == nil {
-			t.CompletedAt = &now
+	entries := make([]journalEntry, 0, len(tasks))
+	for _, t := range tasks {
+		if s.findUnsafe(t.ID) != nil || s.findTrashUnsafe(t.ID) != nil {
+			return fmt.Errorf("import task %d: %w", t.ID, ErrExists)
 		}
-	} else {
-		t.CompletedAt = nil
+		cp := cloneTask(t)
+		ensureUUID(cp, nil)
+		entries = append(entries, journalEntry{Op: opAdd, At: now, Task: cp})
 	}
-	t.UpdatedAt = now
+	return s.commitUnsafe(entries...)
+}
 
-	if err := s.saveUnsafe(); err != nil {
-		return nil, err
+// ReplaceAll swaps every task in the store for tasks, keeping their IDs and
+// timestamps, and rewrites the snapshot. Trashed tasks stay in the trash
+// unless one of the new tasks takes their ID.
+func (s *Store) ReplaceAll(tasks []*Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.beginUnsafe(); err != nil {
+		return err
 	}
-	cp := *t
-	cp.Due = cloneTimePtr(t.Due)
-	cp.CompletedAt = cloneTimePtr(t.CompletedAt)
-	return &cp, nil
+	defer s.unlockUnsafe()
+
+	s.tasks = make([]*Task, 0, len(tasks))
+	for _, t := range tasks {
+		cp := cloneTask(t)
+		ensureUUID(cp, nil)
+		s.tasks = append(s.tasks, cp)
+		s.trash = removeTask(s.trash, t.ID)
+	}
+	s.index = newTaskIndex(s.tasks)
+	s.resetNextIDUnsafe()
+	if err := s.compactUnsafe(); err != nil {
+		return err
+	}
+	return s.gitCommitUnsafe(fmt.Sprintf("replace all tasks with %d tasks", len(tasks)))
 }
 
-// ToggleCompleted flips completion state and saves it.
-func (s *Store) ToggleCompleted(id int) (*Task, error) {
+// --- helpers ---
+
+// modify applies fn to a copy of the task and journals the result as op.
+// The copy starts from the current state on disk; if that is not the state
+// this process last saw, the change is refused with ErrConflict.
+func (s *Store) modify(id int, op string, fn func(t *Task, now time.Time) error) (*Task, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	t := s.findUnsafe(id)
-	if t == nil {
+	seen := s.findUnsafe(id)
+	if seen == nil {
 		return nil, ErrNotFound
 	}
-
-	now := time.Now()
-	if t.CompletedAt == nil {
-		t.CompletedAt = &now
-	} else {
-		t.CompletedAt = nil
+	if err := s.beginUnsafe(); err != nil {
+		return nil, err
 	}
-	t.UpdatedAt = now
+	defer s.unlockUnsafe()
 
-	if err := s.saveUnsafe(); err != nil {
+	cur, err := s.checkUnsafe(seen)
+	if err != nil {
 		return nil, err
 	}
-	cp := *t
-	cp.Due = cloneTimePtr(t.Due)
-	cp.CompletedAt = cloneTimePtr(t.CompletedAt)
-	return &cp, nil
+	e, err := changeEntry(cur, op, fn)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.commitUnsafe(e); err != nil {
+		return nil, err
+	}
+	return cloneTask(e.Task), nil
 }
 
-// Delete removes a task and saves it.
-func (s *Store) Delete(id int) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// changeEntry returns the journal entry that applies fn to a copy of cur
+// under the next revision.
+func changeEntry(cur *Task, op string, fn func(t *Task, now time.Time) error) (journalEntry, error) {
+	t := cloneTask(cur)
+	now := time.Now()
+	if err := fn(t, now); err != nil {
+		return journalEntry{}, err
+	}
+	recordChanges(cur, t, now)
+	t.UpdatedAt = now
+	t.Revision++
+	return journalEntry{Op: op, At: now, Task: t}, nil
+}
 
-	idx := -1
-	for i, t := range s.tasks {
-		if t.ID == id {
-			idx = i
-			break
+// sortTasks orders tasks the way every store lists them:
+// 1) incomplete first in openOrder: by priority (highest first), then by
+// due date (nil due goes last),
+// 2) then completed by completion time (newest first),
+// 3) finally by ID for stability.
+func sortTasks(out []*Task) {
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+
+		// Incomplete before complete
+		if a.IsCompleted() != b.IsCompleted() {
+			return !a.IsCompleted()
+		}
+
+		// If both incomplete: by priority, due date, then ID
+		if !a.IsCompleted() {
+			return openOrder(a, b)
 		}
-	}
-	if idx == -1 {
-		return ErrNotFound
-	}
 
-	// Remove without preserving order (but here we preserve for readability).
-	s.tasks = append(s.tasks[:idx], s.tasks[idx+1:]...)
-	return s.saveUnsafe()
+		// If both complete: newest completion first
+		if a.CompletedAt != nil && b.CompletedAt != nil && !a.CompletedAt.Equal(*b.CompletedAt) {
+			return b.CompletedAt.Before(*a.CompletedAt)
+		}
+		return a.ID < b.ID
+	})
 }
 
-// --- helpers ---
+// resetNextIDUnsafe determines nextID from max existing ID. Trashed tasks
+// count too, so a restored task never clashes with a newer one, and the
+// IDs of archived tasks stay taken through idFloor.
+func (s *Store) resetNextIDUnsafe() {
+	maxID := 0
+	for _, list := range [][]*Task{s.tasks, s.trash} {
+		for _, t := range list {
+			if t.ID > maxID {
+				maxID = t.ID
+			}
+		}
+	}
+	s.NextID = max(maxID+1, s.idFloor)
+}
 
 func (s *Store) findUnsafe(id int) *Task {
-	for _, t := range s.tasks {
-		if t.ID == id {
-			return t
+	return s.index.byID[id]
+}
+
+// readSnapshotUnsafe replaces the in-memory tasks with the snapshot on disk,
+// migrating it from older schema versions. A missing snapshot means an
+// empty store.
+func (s *Store) readSnapshotUnsafe() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		// If file doesn't exist yet, that's fine—start empty.
+		if errors.Is(err, os.ErrNotExist) {
+			s.tasks, s.trash = []*Task{}, []*Task{}
+			s.index = newTaskIndex(nil)
+			s.version, s.extra = CurrentVersion, nil
+			return nil
 		}
+		return fmt.Errorf("open store: %w", err)
 	}
+
+	if data, err = unseal(s.opts.secret, data); err != nil {
+		return fmt.Errorf("open store: %w", err)
+	}
+	snap, err := decodeSnapshot(data)
+	if err != nil {
+		return fmt.Errorf("decode store: %w", err)
+	}
+	s.tasks, s.trash = snap.Tasks, snap.Trash
+	if s.tasks == nil {
+		s.tasks = []*Task{}
+	}
+	if s.trash == nil {
+		s.trash = []*Task{}
+	}
+	s.index = newTaskIndex(s.tasks)
+	s.version, s.extra = snap.Version, snap.extra
+	s.idFloor = snap.NextID
+	s.archive = nil
 	return nil
 }
 
+// saveUnsafe writes the snapshot atomically. The caller holds the lock.
 func (s *Store) saveUnsafe() error {
-	// We are holding the write lock; call the public Save via a thin wrapper
-	// that doesn't attempt to lock again.
-	dir := filepath.Dir(s.path)
+	payload := snapshot{
+		Version: max(s.version, CurrentVersion),
+		Tasks:   s.tasks,
+		Trash:   s.trash,
+		NextID:  s.NextID,
+		extra:   s.extra,
+	}
+	data, err := json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode store: %w", err)
+	}
+	if data, err = sealWith(s.opts.secret, data); err != nil {
+		return fmt.Errorf("encrypt store: %w", err)
+	}
+	return writeFileAtomic(s.path, append(data, '\n'))
+}
+
+// writeFileAtomic replaces the file at path with data via a synced temp
+// file, so readers see either the old or the new contents.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return fmt.Errorf("mkdir store dir: %w", err)
 	}
@@ -428,16 +762,10 @@ func (s *Store) saveUnsafe() error {
 	}
 	tmpPath := tmp.Name()
 
-	enc := json.NewEncoder(tmp)
-	enc.SetIndent("", "  ")
-	payload := struct {
-		Tasks []*Task `json:"tasks"`
-	}{Tasks: s.tasks}
-
-	if err := enc.Encode(payload); err != nil {
+	if _, err := tmp.Write(data); err != nil {
 		tmp.Close()
 		os.Remove(tmpPath)
-		return fmt.Errorf("encode store: %w", err)
+		return fmt.Errorf("write temp: %w", err)
 	}
 	if err := tmp.Sync(); err != nil {
 		tmp.Close()
@@ -448,7 +776,7 @@ func (s *Store) saveUnsafe() error {
 		os.Remove(tmpPath)
 		return fmt.Errorf("close temp: %w", err)
 	}
-	if err := os.Rename(tmpPath, s.path); err != nil {
+	if err := os.Rename(tmpPath, path); err != nil {
 		os.Remove(tmpPath)
 		return fmt.Errorf("rename store: %w", err)
 	}
@@ -459,6 +787,15 @@ func (s *Store) saveUnsafe() error {
 	return nil
 }
 
+func cloneTask(t *Task) *Task {
+	cp := *t
+	cp.Tags = append([]string(nil), t.Tags...)
+	cp.Due = cloneTimePtr(t.Due)
+	cp.CompletedAt = cloneTimePtr(t.CompletedAt)
+	cp.DeletedAt = cloneTimePtr(t.DeletedAt)
+	return &cp
+}
+
 func cloneTimePtr(t *time.Time) *time.Time {
 	if t == nil {
 		return nil
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Priority is how urgent a task is. Open tasks are listed by priority,
+// highest first, before their due date.
+type Priority int
+
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+	PriorityCritical
+)
+
+// Priorities lists the priorities from lowest to highest.
+var Priorities = []Priority{PriorityNone, PriorityLow, PriorityMedium, PriorityHigh, PriorityCritical}
+
+var priorityNames = []string{"none", "low", "medium", "high", "critical"}
+
+func (p Priority) String() string {
+	if !p.Valid() {
+		return fmt.Sprintf("Priority(%d)", int(p))
+	}
+	return priorityNames[p]
+}
+
+// Valid reports whether p is one of Priorities.
+func (p Priority) Valid() bool {
+	return p >= PriorityNone && p <= PriorityCritical
+}
+
+// Raise returns the next higher priority, or p if it is the highest.
+func (p Priority) Raise() Priority {
+	return min(p+1, PriorityCritical)
+}
+
+// Lower returns the next lower priority, or p if it is the lowest.
+func (p Priority) Lower() Priority {
+	return max(p-1, PriorityNone)
+}
+
+// ParsePriority returns the priority with the given name, e.g. "high".
+func ParsePriority(s string) (Priority, error) {
+	for i, name := range priorityNames {
+		if strings.EqualFold(s, name) {
+			return Priority(i), nil
+		}
+	}
+	return PriorityNone, fmt.Errorf("invalid priority %q, want one of %s", s, strings.Join(priorityNames, ", "))
+}
+
+// MarshalText and UnmarshalText keep priorities by name in the JSON store
+// and in exports.
+func (p Priority) MarshalText() ([]byte, error) {
+	if !p.Valid() {
+		return nil, fmt.Errorf("invalid priority %d", int(p))
+	}
+	return []byte(p.String()), nil
+}
+
+func (p *Priority) UnmarshalText(text []byte) error {
+	v, err := ParsePriority(string(text))
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
@@ -0,0 +1,23 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPriorityJSON(t *testing.T) {
+	data, err := json.Marshal(&Task{Title: "a", Priority: PriorityHigh})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var got Task
+	if err := json.Unmarshal(data, &got); err != nil || got.Priority != PriorityHigh {
+		t.Errorf("Unmarshal(%s) = %v, %v, want high", data, got.Priority, err)
+	}
+	if err := json.Unmarshal([]byte(`{"priority":"urgent"}`), &got); err == nil {
+		t.Error("Unmarshal() of an unknown priority succeeded")
+	}
+	if PriorityCritical.Raise() != PriorityCritical || PriorityNone.Lower() != PriorityNone {
+		t.Error("Raise() or Lower() went past the last priority")
+	}
+}
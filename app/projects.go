@@ -0,0 +1,99 @@
+package app
+
+import (
+	"sort"
+	"strings"
+)
+
+// Projects are dotted paths like "work.backend.billing". A task in a
+// project is in every project above it too, so "work" holds the tasks of
+// "work.backend".
+
+// NormalizeProject returns the project path p in lowercase, without empty
+// parts or the space around them, e.g. "work.backend" for " Work..Backend ".
+func NormalizeProject(p string) string {
+	var parts []string
+	for _, part := range strings.Split(p, ".") {
+		if part = strings.ToLower(strings.TrimSpace(part)); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// InProject reports whether the task is in the project with the given
+// path or in one of its subprojects.
+func (t *Task) InProject(path string) bool {
+	return isProjectIn(t.Project, path)
+}
+
+// isProjectIn reports whether project is the one with the given path or
+// one below it.
+func isProjectIn(project, path string) bool {
+	return project == path || strings.HasPrefix(project, path+".")
+}
+
+// showProject formats a project for the history and merge reports.
+func showProject(v any) string {
+	if v == "" {
+		return "none"
+	}
+	return v.(string)
+}
+
+// ProjectNode is a project in the tree of projects.
+type ProjectNode struct {
+	Name     string // the last part of Path
+	Path     string
+	Open     int // open tasks of the project and its subprojects
+	Children []*ProjectNode
+}
+
+// ProjectTree returns the projects of tasks as a tree, each level sorted
+// by name. Projects above those of the tasks are part of it too.
+func ProjectTree(tasks []*Task) []*ProjectNode {
+	root := &ProjectNode{}
+	nodes := map[string]*ProjectNode{"": root}
+	for _, t := range tasks {
+		if t.Project == "" {
+			continue
+		}
+		parent, path := root, ""
+		for _, name := range strings.Split(t.Project, ".") {
+			if path != "" {
+				path += "."
+			}
+			path += name
+			n, ok := nodes[path]
+			if !ok {
+				n = &ProjectNode{Name: name, Path: path}
+				nodes[path] = n
+				parent.Children = append(parent.Children, n)
+			}
+			if !t.IsCompleted() {
+				n.Open++
+			}
+			parent = n
+		}
+	}
+	for _, n := range nodes {
+		sort.Slice(n.Children, func(i, j int) bool { return n.Children[i].Name < n.Children[j].Name })
+	}
+	return root.Children
+}
+
+// Walk calls fn for n and then for each project below it, depth first,
+// with the depth below n. If fn returns false, the projects below the one
+// it was called for are skipped.
+func (n *ProjectNode) Walk(fn func(n *ProjectNode, depth int) bool) {
+	n.walk(fn, 0)
+}
+
+func (n *ProjectNode) walk(fn func(n *ProjectNode, depth int) bool, depth int) {
+	if !fn(n, depth) {
+		return
+	}
+	for _, c := range n.Children {
+		c.walk(fn, depth+1)
+	}
+}
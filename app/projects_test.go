@@ -0,0 +1,47 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeProject(t *testing.T) {
+	for in, want := range map[string]string{
+		"":                     "",
+		"work":                 "work",
+		" Work..Backend . ":    "work.backend",
+		"work.backend.billing": "work.backend.billing",
+	} {
+		if got := NormalizeProject(in); got != want {
+			t.Errorf("NormalizeProject(%q) = %q, want %q", in, got, want)
+		}
+	}
+	task := &Task{Project: "work.backend"}
+	if !task.InProject("work") || !task.InProject("work.backend") || task.InProject("work.back") {
+		t.Errorf("InProject() of %q is wrong", task.Project)
+	}
+}
+
+func TestProjectTree(t *testing.T) {
+	done := time.Now()
+	tasks := []*Task{
+		{Project: "work.backend.billing"},
+		{Project: "work.backend"},
+		{Project: "work.frontend", CompletedAt: &done},
+		{Project: "home"},
+		{},
+	}
+	var lines []string
+	for _, n := range ProjectTree(tasks) {
+		n.Walk(func(n *ProjectNode, depth int) bool {
+			lines = append(lines, fmt.Sprintf("%s%s %d", strings.Repeat(" ", depth), n.Name, n.Open))
+			return true
+		})
+	}
+	want := "home 1|work 2| backend 2|  billing 1| frontend 0"
+	if got := strings.Join(lines, "|"); got != want {
+		t.Errorf("ProjectTree() = %s, want %s", got, want)
+	}
+}
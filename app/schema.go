@@ -0,0 +1,195 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// CurrentVersion is the schema version this build writes. Files without a
+// version field predate versioning and are treated as version 1. Optional
+// fields, like the trash or the tags of a task, do not change it: builds
+// that do not know them keep them as they are. Only a change that existing
+// files have to be rewritten for does, with its migration.
+//
+//	1: tasks only
+//	2: per-task revision counter
+const CurrentVersion = 2
+
+// migration upgrades a decoded store file by exactly one version. It works
+// on generic JSON so it does not depend on today's Go types.
+type migration func(doc map[string]any) error
+
+// migrations[v] upgrades a file from version v to v+1.
+var migrations = map[int]migration{}
+
+func registerMigration(from int, fn migration) {
+	if _, dup := migrations[from]; dup {
+		panic(fmt.Sprintf("migration from version %d registered twice", from))
+	}
+	migrations[from] = fn
+}
+
+func init() {
+	registerMigration(1, func(doc map[string]any) error {
+		return eachTask(doc, func(t map[string]any) {
+			if r, ok := t["revision"].(json.Number); !ok || r.String() == "0" {
+				t["revision"] = 1
+			}
+		})
+	})
+}
+
+// snapshot is the envelope of the JSON store file. Fields this build does
+// not know about, e.g. written by a newer version, are kept in extra and
+// written back unchanged.
+type snapshot struct {
+	Version int     `json:"version"`
+	Tasks   []*Task `json:"tasks"`
+	Trash   []*Task `json:"trash,omitempty"`
+	NextID  int     `json:"next_id,omitempty"`
+	extra   map[string]json.RawMessage
+}
+
+func (s *snapshot) UnmarshalJSON(data []byte) error {
+	type plain snapshot
+	extra, err := unmarshalKnown(data, (*plain)(s))
+	if err != nil {
+		return err
+	}
+	s.extra = extra
+	return nil
+}
+
+func (s snapshot) MarshalJSON() ([]byte, error) {
+	type plain snapshot
+	return marshalWithExtra(plain(s), s.extra)
+}
+
+// decodeSnapshot parses a store file, upgrading it to CurrentVersion.
+// Files from newer versions are loaded as they are.
+func decodeSnapshot(data []byte) (*snapshot, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return &snapshot{Version: CurrentVersion}, nil
+	}
+
+	var probe struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, err
+	}
+	version := probe.Version
+	if version == 0 {
+		version = 1
+	}
+
+	if version < CurrentVersion {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var doc map[string]any
+		if err := dec.Decode(&doc); err != nil {
+			return nil, err
+		}
+		for ; version < CurrentVersion; version++ {
+			migrate, ok := migrations[version]
+			if !ok {
+				return nil, fmt.Errorf("no migration from version %d", version)
+			}
+			if err := migrate(doc); err != nil {
+				return nil, fmt.Errorf("migrate from version %d: %w", version, err)
+			}
+		}
+		doc["version"] = version
+		var err error
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, err
+		}
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, err
+	}
+	snap.Version = version
+	return &snap, nil
+}
+
+// eachTask calls fn for every task object in a generic store document.
+func eachTask(doc map[string]any, fn func(t map[string]any)) error {
+	raw, ok := doc["tasks"]
+	if !ok || raw == nil {
+		return nil
+	}
+	tasks, ok := raw.([]any)
+	if !ok {
+		return fmt.Errorf("tasks is %T, want array", raw)
+	}
+	for i, v := range tasks {
+		t, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("task %d is %T, want object", i, v)
+		}
+		fn(t)
+	}
+	return nil
+}
+
+// unmarshalKnown decodes data into v and returns the object members that
+// v has no field for.
+func unmarshalKnown(data []byte, v any) (map[string]json.RawMessage, error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	for _, k := range jsonKeys(reflect.TypeOf(v).Elem()) {
+		delete(all, k)
+	}
+	if len(all) == 0 {
+		return nil, nil
+	}
+	return all, nil
+}
+
+// marshalWithExtra encodes v and adds the members of extra it does not set.
+func marshalWithExtra(v any, extra map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	for k, raw := range extra {
+		if _, ok := all[k]; !ok {
+			all[k] = raw
+		}
+	}
+	return json.Marshal(all)
+}
+
+// jsonKeys lists the JSON member names of a struct type's exported fields.
+func jsonKeys(t reflect.Type) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+		keys = append(keys, name)
+	}
+	return keys
+}
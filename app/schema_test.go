@@ -0,0 +1,116 @@
+package app
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// loadFixture copies testdata/schema/name into a temp dir and loads it.
+func loadFixture(t *testing.T, name string) (*Store, string) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "schema", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load(%s) error = %v", name, err)
+	}
+	return s, path
+}
+
+func TestSchemaMigrations(t *testing.T) {
+	tests := []struct {
+		fixture      string
+		wantVersion  int
+		wantTasks    int
+		wantRevision int
+	}{
+		{fixture: "v1.json", wantVersion: CurrentVersion, wantTasks: 2, wantRevision: 1},
+		{fixture: "v2.json", wantVersion: CurrentVersion, wantTasks: 1, wantRevision: 3},
+		{fixture: "future.json", wantVersion: 99, wantTasks: 1, wantRevision: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			s, path := loadFixture(t, tt.fixture)
+			if s.version != tt.wantVersion {
+				t.Errorf("version = %d, want %d", s.version, tt.wantVersion)
+			}
+			tasks := s.List()
+			if len(tasks) != tt.wantTasks {
+				t.Fatalf("len(List()) = %d, want %d", len(tasks), tt.wantTasks)
+			}
+			for _, task := range tasks {
+				if task.Revision != tt.wantRevision {
+					t.Errorf("task %d revision = %d, want %d", task.ID, task.Revision, tt.wantRevision)
+				}
+			}
+
+			// Saving writes the migrated file back, still at the same version.
+			if err := s.Save(); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			var onDisk struct {
+				Version int `json:"version"`
+			}
+			data, _ := os.ReadFile(path)
+			if err := json.Unmarshal(data, &onDisk); err != nil {
+				t.Fatal(err)
+			}
+			if onDisk.Version != tt.wantVersion {
+				t.Errorf("saved version = %d, want %d", onDisk.Version, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestSchemaKeepsUnknownFields(t *testing.T) {
+	s, path := loadFixture(t, "future.json")
+
+	// Unknown task fields survive a mutation through the journal...
+	title := "Write the report"
+	if _, err := s.Update(1, UpdateOptions{Title: &title}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := s.Add("New", "", nil, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	got, _ := reloaded.Get(1)
+	if string(got.Extra["color"]) != `"teal"` {
+		t.Errorf("Extra[color] after replay = %s, want \"teal\"", got.Extra["color"])
+	}
+
+	// ...and both task and top-level fields survive compaction.
+	if err := reloaded.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	var onDisk struct {
+		Labels []string         `json:"labels"`
+		Tasks  []map[string]any `json:"tasks"`
+	}
+	data, _ := os.ReadFile(path)
+	if err := json.Unmarshal(data, &onDisk); err != nil {
+		t.Fatal(err)
+	}
+	if len(onDisk.Labels) != 2 {
+		t.Errorf("labels = %v, want [work home]", onDisk.Labels)
+	}
+	for _, task := range onDisk.Tasks {
+		if task["id"] == 1.0 {
+			if task["color"] != "teal" || task["checklist"] == nil || task["title"] != title {
+				t.Errorf("task 1 on disk = %v, want unknown fields kept", task)
+			}
+		}
+	}
+}
@@ -0,0 +1,824 @@
+package app
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteMigrations upgrade the database schema; PRAGMA user_version holds
+// the number of migrations already applied.
+var sqliteMigrations = []string{
+	`CREATE TABLE IF NOT EXISTS tasks (
+		id           INTEGER PRIMARY KEY,
+		day          TEXT NOT NULL,
+		date         TEXT NOT NULL,
+		title        TEXT NOT NULL,
+		notes        TEXT NOT NULL DEFAULT '',
+		due          TEXT,
+		created_at   TEXT NOT NULL,
+		updated_at   TEXT NOT NULL,
+		completed_at TEXT
+	);
+	CREATE INDEX IF NOT EXISTS tasks_day ON tasks (day);`,
+	`ALTER TABLE tasks ADD COLUMN revision INTEGER NOT NULL DEFAULT 1;`,
+	`ALTER TABLE tasks ADD COLUMN deleted_at TEXT;`,
+	`CREATE INDEX IF NOT EXISTS tasks_open ON tasks (day) WHERE completed_at IS NULL AND deleted_at IS NULL;`,
+	// Rows without a UUID are given the legacy one by backfillSQLite.
+	`ALTER TABLE tasks ADD COLUMN uuid TEXT;
+	CREATE INDEX IF NOT EXISTS tasks_uuid ON tasks (uuid);`,
+	// The history of a task, as JSON; see formatChanges.
+	`ALTER TABLE tasks ADD COLUMN changes TEXT;`,
+	// The tags of a task, as JSON; see formatTags.
+	`ALTER TABLE tasks ADD COLUMN tags TEXT;`,
+	// The priority of a task; see Priority.
+	`ALTER TABLE tasks ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;`,
+	// The project of a task, a dotted path; see NormalizeProject.
+	`ALTER TABLE tasks ADD COLUMN project TEXT NOT NULL DEFAULT '';`,
+	// The day part of completed_at, for CompletedOn.
+	`CREATE INDEX IF NOT EXISTS tasks_done ON tasks (substr(completed_at, 1, 10)) WHERE completed_at IS NOT NULL;`,
+	// IDs are never reused, not even those of purged tasks. Only a new
+	// table can be AUTOINCREMENT.
+	`CREATE TABLE tasks_new (
+		id           INTEGER PRIMARY KEY AUTOINCREMENT,
+		uuid         TEXT,
+		day          TEXT NOT NULL,
+		date         TEXT NOT NULL,
+		title        TEXT NOT NULL,
+		notes        TEXT NOT NULL DEFAULT '',
+		tags         TEXT,
+		priority     INTEGER NOT NULL DEFAULT 0,
+		project      TEXT NOT NULL DEFAULT '',
+		due          TEXT,
+		created_at   TEXT NOT NULL,
+		updated_at   TEXT NOT NULL,
+		completed_at TEXT,
+		revision     INTEGER NOT NULL DEFAULT 1,
+		deleted_at   TEXT,
+		changes      TEXT
+	);
+	INSERT INTO tasks_new (id, uuid, day, date, title, notes, tags, priority, project, due, created_at, updated_at, completed_at, revision, deleted_at, changes)
+	SELECT id, uuid, day, date, title, notes, tags, priority, project, due, created_at, updated_at, completed_at, revision, deleted_at, changes FROM tasks;
+	DROP TABLE tasks;
+	ALTER TABLE tasks_new RENAME TO tasks;
+	CREATE INDEX tasks_day ON tasks (day);
+	CREATE INDEX tasks_open ON tasks (day) WHERE completed_at IS NULL AND deleted_at IS NULL;
+	CREATE INDEX tasks_uuid ON tasks (uuid);
+	CREATE INDEX tasks_done ON tasks (substr(completed_at, 1, 10)) WHERE completed_at IS NOT NULL;`,
+	// For ListProject.
+	`CREATE INDEX IF NOT EXISTS tasks_project ON tasks (project) WHERE project != '';`,
+}
+
+const sqliteColumns = `id, uuid, date, title, notes, due, created_at, updated_at, completed_at, revision, deleted_at, changes, tags, priority, project`
+
+// sqliteLive selects the tasks that are not in the trash.
+const sqliteLive = `deleted_at IS NULL`
+
+// SQLiteStore manages tasks persisted in an embedded SQLite database.
+// Every mutation touches only the affected row.
+// It is safe for concurrent use.
+type SQLiteStore struct {
+	sqliteReads
+	db      *sql.DB
+	watcher *fsnotify.Watcher
+	auto    *autoBackup
+
+	subscribers
+}
+
+// OpenSQLite opens (or creates) a task store backed by the given SQLite file.
+func OpenSQLite(path string, opts ...Option) (*SQLiteStore, error) {
+	o := newOptions(opts)
+	if o.secret != nil {
+		return nil, fmt.Errorf("encryption is only supported by the %s backend", BackendJSON)
+	}
+	if o.git {
+		return nil, fmt.Errorf("git-backed stores are only supported by the %s backend", BackendJSON)
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("mkdir store dir: %w", err)
+		}
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("open store: %w", err)
+	}
+	// A single connection keeps writes serialized inside the process.
+	db.SetMaxOpenConns(1)
+
+	for _, stmt := range []string{
+		"PRAGMA journal_mode = WAL",
+		"PRAGMA busy_timeout = 5000",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("init store: %w", err)
+		}
+	}
+	if err := migrateSQLite(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := backfillSQLite(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	s := &SQLiteStore{
+		sqliteReads: sqliteReads{conn: db, path: path, readErr: &readErr{}},
+		db:          db,
+		auto:        o.autoBackup(path),
+	}
+	if o.trashRetention > 0 {
+		if _, err := s.PurgeTrash(time.Now().Add(-o.trashRetention)); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// migrateSQLite applies the schema migrations the database has not seen yet.
+func migrateSQLite(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+	for i := version; i < len(sqliteMigrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("begin migration: %w", err)
+		}
+		if _, err := tx.Exec(sqliteMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migrate schema to %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migrate schema to %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migrate schema to %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// backfillSQLite gives the tasks saved without a UUID, by an older version,
+// the one derived from their ID and creation time.
+func backfillSQLite(db *sql.DB) error {
+	rows, err := db.Query(`SELECT id, created_at FROM tasks WHERE uuid IS NULL`)
+	if err != nil {
+		return fmt.Errorf("query tasks without uuid: %w", err)
+	}
+	uuids := map[int]string{}
+	for rows.Next() {
+		var (
+			id      int
+			created string
+		)
+		if err := rows.Scan(&id, &created); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan task: %w", err)
+		}
+		createdAt, err := parseTime(created)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		uuids[id] = legacyUUID(id, createdAt)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("scan tasks: %w", err)
+	}
+	for id, u := range uuids {
+		if _, err := db.Exec(`UPDATE tasks SET uuid = ? WHERE id = ? AND uuid IS NULL`, u, id); err != nil {
+			return fmt.Errorf("backfill uuid of task %d: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// Reload is a no-op: every read goes to the database.
+func (s *SQLiteStore) Reload() error {
+	return nil
+}
+
+// Close stops watching and releases the database handle.
+func (s *SQLiteStore) Close() error {
+	if s.watcher != nil {
+		s.watcher.Close()
+		s.watcher = nil
+	}
+	return s.db.Close()
+}
+
+// sqlConn is what the statements of a SQLiteStore run on: the database,
+// or the transaction of Tx.
+type sqlConn interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// sqliteReads are the reads of a SQLiteStore, run on conn, for the store
+// and for the view of it inside Tx.
+type sqliteReads struct {
+	conn    sqlConn
+	path    string
+	readErr *readErr
+}
+
+// readErr is the error of the last read of a SQLiteStore that failed.
+type readErr struct {
+	mu  sync.Mutex
+	err error
+}
+
+// List returns all tasks, sorted like Store.List.
+func (s sqliteReads) List() []*Task {
+	out := s.read(`SELECT ` + sqliteColumns + ` FROM tasks WHERE ` + sqliteLive)
+	sortTasks(out)
+	return out
+}
+
+// ListByDate returns the tasks scheduled for the given day, sorted like List.
+func (s sqliteReads) ListByDate(date time.Time) []*Task {
+	out := s.read(`SELECT `+sqliteColumns+` FROM tasks WHERE day = ? AND `+sqliteLive, dayKey(date))
+	sortTasks(out)
+	return out
+}
+
+// ListRange returns the tasks scheduled from the day of from through the
+// day of to, by day and then sorted like List.
+func (s sqliteReads) ListRange(from, to time.Time) []*Task {
+	out := s.read(`SELECT `+sqliteColumns+` FROM tasks WHERE day BETWEEN ? AND ? AND `+sqliteLive, dayKey(from), dayKey(to))
+	sortTasks(out)
+	sort.SliceStable(out, func(i, j int) bool { return dayKey(out[i].Date) < dayKey(out[j].Date) })
+	return out
+}
+
+// Overdue returns the open tasks scheduled before the day of now, by
+// priority, then due date (tasks without one last), then by ID.
+func (s sqliteReads) Overdue(now time.Time) []*Task {
+	out := s.read(`SELECT `+sqliteColumns+` FROM tasks WHERE day < ? AND completed_at IS NULL AND `+sqliteLive, dayKey(now))
+	sortTasks(out)
+	return out
+}
+
+// ListOpen returns the open tasks, sorted like Overdue.
+func (s sqliteReads) ListOpen() []*Task {
+	out := s.read(`SELECT ` + sqliteColumns + ` FROM tasks WHERE completed_at IS NULL AND ` + sqliteLive)
+	sortTasks(out)
+	return out
+}
+
+// CompletedOn returns the tasks completed on the day of day, sorted like
+// List. completed_at starts with that day in the time zone it was stored
+// in, which is how dayKey reads CompletedAt too.
+func (s sqliteReads) CompletedOn(day time.Time) []*Task {
+	out := s.read(`SELECT `+sqliteColumns+` FROM tasks WHERE substr(completed_at, 1, 10) = ? AND completed_at IS NOT NULL AND `+sqliteLive, dayKey(day))
+	sortTasks(out)
+	return out
+}
+
+// ListProject returns the tasks in the project with the given path or one
+// below it, or in any project if path is empty, sorted like List. The
+// projects below "a" are those from "a." up to "a/", '/' being the
+// character after '.'.
+func (s sqliteReads) ListProject(path string) []*Task {
+	var out []*Task
+	if path == "" {
+		out = s.read(`SELECT ` + sqliteColumns + ` FROM tasks WHERE project != '' AND ` + sqliteLive)
+	} else {
+		out = s.read(`SELECT `+sqliteColumns+` FROM tasks WHERE (project = ? OR (project >= ? AND project < ?)) AND `+sqliteLive, path, path+".", path+"/")
+	}
+	sortTasks(out)
+	return out
+}
+
+// Add creates a new task.
+func (s *SQLiteStore) Add(title, notes string, due *time.Time, date time.Time) (*Task, error) {
+	if title == "" {
+		return nil, ErrTitleRequired
+	}
+	if err := s.backupIfDue(); err != nil {
+		return nil, err
+	}
+	t, err := addTask(s.db, title, notes, due, date)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(opAdd, t)
+	return t, nil
+}
+
+// addTask inserts a new task under the next free ID.
+func addTask(c sqlConn, title, notes string, due *time.Time, date time.Time) (*Task, error) {
+	now := time.Now()
+	t := &Task{
+		UUID:      newUUID(),
+		Date:      date,
+		Title:     title,
+		Notes:     notes,
+		Due:       cloneTimePtr(due),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Revision:  1,
+	}
+	res, err := c.Exec(
+		`INSERT INTO tasks (uuid, day, date, title, notes, due, created_at, updated_at, completed_at, revision)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.UUID, dayKey(t.Date), formatTime(t.Date), t.Title, t.Notes, formatTimePtr(t.Due),
+		formatTime(t.CreatedAt), formatTime(t.UpdatedAt), nil, t.Revision,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("insert task: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("insert task: %w", err)
+	}
+	t.ID = int(id)
+	return t, nil
+}
+
+// Get returns the task with the given ID.
+func (s sqliteReads) Get(id int) (*Task, error) {
+	out, err := queryTasks(s.conn, `SELECT `+sqliteColumns+` FROM tasks WHERE id = ? AND `+sqliteLive, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, ErrNotFound
+	}
+	return out[0], nil
+}
+
+// Update modifies a task.
+func (s *SQLiteStore) Update(id int, opts UpdateOptions) (*Task, error) {
+	return s.modify(id, false, opUpdate, updateTask(opts))
+}
+
+// MarkCompleted sets or clears completion.
+func (s *SQLiteStore) MarkCompleted(id int, completed bool) (*Task, error) {
+	return s.modify(id, false, opComplete, markCompleted(completed))
+}
+
+// ToggleCompleted flips completion state.
+func (s *SQLiteStore) ToggleCompleted(id int) (*Task, error) {
+	return s.modify(id, false, opComplete, toggleCompleted)
+}
+
+// Delete moves a task to the trash.
+func (s *SQLiteStore) Delete(id int) error {
+	_, err := s.modify(id, false, opDelete, deleteTask)
+	return err
+}
+
+// Put stores a fully-formed task as given.
+func (s *SQLiteStore) Put(t *Task) (*Task, error) {
+	if t.Title == "" {
+		return nil, ErrTitleRequired
+	}
+	if err := s.backupIfDue(); err != nil {
+		return nil, err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin put: %w", err)
+	}
+	defer tx.Rollback()
+
+	cp, op, err := putTask(tx, t)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit put: %w", err)
+	}
+	s.publish(op, cp)
+	return cp, nil
+}
+
+// putTask stores t as given, replacing the task with its ID, and returns
+// what it stored with the op of the change.
+func putTask(c sqlConn, t *Task) (*Task, string, error) {
+	cp := cloneTask(t)
+	cp.DeletedAt = nil
+	op := opAdd
+	if cur, err := getTask(c, t.ID); err == nil {
+		if cur.DeletedAt == nil {
+			op = opUpdate
+		}
+		cp.Revision = max(cp.Revision, cur.Revision)
+		ensureUUID(cp, cur)
+	}
+	ensureUUID(cp, nil)
+	cp.Revision++
+
+	if _, err := c.Exec(`DELETE FROM tasks WHERE id = ?`, cp.ID); err != nil {
+		return nil, "", fmt.Errorf("put task: %w", err)
+	}
+	if err := insertTx(c, cp); err != nil {
+		return nil, "", fmt.Errorf("put task: %w", err)
+	}
+	return cp, op, nil
+}
+
+// Import adds fully-formed tasks, keeping their IDs and timestamps,
+// in a single transaction.
+func (s *SQLiteStore) Import(tasks []*Task) error {
+	if err := s.backupIfDue(); err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin import: %w", err)
+	}
+	defer tx.Rollback()
+
+	added := make([]*Task, 0, len(tasks))
+	for _, t := range tasks {
+		if _, err := getTask(tx, t.ID); err == nil {
+			return fmt.Errorf("import task %d: %w", t.ID, ErrExists)
+		}
+		t = cloneTask(t)
+		ensureUUID(t, nil)
+		if err := insertTx(tx, t); err != nil {
+			return fmt.Errorf("import task %d: %w", t.ID, err)
+		}
+		added = append(added, t)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, t := range added {
+		if t.DeletedAt == nil {
+			s.publish(opAdd, t)
+		}
+	}
+	return nil
+}
+
+// ReplaceAll swaps every task in the store for tasks, keeping their IDs and
+// timestamps, in a single transaction. Trashed tasks stay in the trash
+// unless one of the new tasks takes their ID.
+func (s *SQLiteStore) ReplaceAll(tasks []*Task) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin replace: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM tasks WHERE ` + sqliteLive); err != nil {
+		return fmt.Errorf("clear tasks: %w", err)
+	}
+	for _, t := range tasks {
+		if _, err := tx.Exec(`DELETE FROM tasks WHERE id = ?`, t.ID); err != nil {
+			return fmt.Errorf("replace task %d: %w", t.ID, err)
+		}
+		t = cloneTask(t)
+		ensureUUID(t, nil)
+		if err := insertTx(tx, t); err != nil {
+			return fmt.Errorf("replace task %d: %w", t.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Tx runs fn in one database transaction, committed if fn returns nil and
+// rolled back otherwise. The events of its changes are published once it
+// is committed. The store has a single connection, which the transaction
+// holds until Tx returns, so fn must only use tx.
+func (s *SQLiteStore) Tx(fn func(tx TaskStore) error) error {
+	if err := s.backupIfDue(); err != nil {
+		return err
+	}
+	dbtx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin batch: %w", err)
+	}
+	defer dbtx.Rollback()
+
+	tx := &sqliteTx{sqliteReads: s.sqliteReads}
+	tx.conn = dbtx
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := dbtx.Commit(); err != nil {
+		return fmt.Errorf("commit batch: %w", err)
+	}
+	for _, c := range tx.changes {
+		s.publish(c.op, c.task)
+	}
+	return nil
+}
+
+// sqliteTx is the view of a SQLiteStore inside SQLiteStore.Tx. Its reads
+// and writes run in the transaction; the ops of its changes are kept to be
+// published after the commit.
+type sqliteTx struct {
+	sqliteReads
+	changes []sqliteChange
+}
+
+type sqliteChange struct {
+	op   string
+	task *Task
+}
+
+func (tx *sqliteTx) Add(title, notes string, due *time.Time, date time.Time) (*Task, error) {
+	if title == "" {
+		return nil, ErrTitleRequired
+	}
+	t, err := addTask(tx.conn, title, notes, due, date)
+	if err != nil {
+		return nil, err
+	}
+	tx.changes = append(tx.changes, sqliteChange{opAdd, t})
+	return t, nil
+}
+
+func (tx *sqliteTx) Put(t *Task) (*Task, error) {
+	if t.Title == "" {
+		return nil, ErrTitleRequired
+	}
+	cp, op, err := putTask(tx.conn, t)
+	if err != nil {
+		return nil, err
+	}
+	tx.changes = append(tx.changes, sqliteChange{op, cp})
+	return cp, nil
+}
+
+func (tx *sqliteTx) Update(id int, opts UpdateOptions) (*Task, error) {
+	return tx.modify(id, opUpdate, updateTask(opts))
+}
+
+func (tx *sqliteTx) MarkCompleted(id int, completed bool) (*Task, error) {
+	return tx.modify(id, opComplete, markCompleted(completed))
+}
+
+func (tx *sqliteTx) ToggleCompleted(id int) (*Task, error) {
+	return tx.modify(id, opComplete, toggleCompleted)
+}
+
+func (tx *sqliteTx) Delete(id int) error {
+	_, err := tx.modify(id, opDelete, deleteTask)
+	return err
+}
+
+func (tx *sqliteTx) modify(id int, op string, fn func(t *Task, now time.Time) error) (*Task, error) {
+	t, err := modifyTask(tx.conn, id, false, fn)
+	if err != nil {
+		return nil, err
+	}
+	tx.changes = append(tx.changes, sqliteChange{op, t})
+	return t, nil
+}
+
+// --- helpers ---
+
+// modify runs modifyTask in a transaction and publishes the event of op.
+func (s *SQLiteStore) modify(id int, trashed bool, op string, fn func(t *Task, now time.Time) error) (*Task, error) {
+	if err := s.backupIfDue(); err != nil {
+		return nil, err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin update: %w", err)
+	}
+	defer tx.Rollback()
+
+	t, err := modifyTask(tx, id, trashed, fn)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit update: %w", err)
+	}
+	s.publish(op, t)
+	return t, nil
+}
+
+// modifyTask loads a task, applies fn and writes it back. The task must be
+// in the trash if trashed is set, and must not be otherwise. The write
+// only succeeds if nobody bumped the revision in the meantime.
+func modifyTask(c sqlConn, id int, trashed bool, fn func(t *Task, now time.Time) error) (*Task, error) {
+	t, err := getTask(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if (t.DeletedAt != nil) != trashed {
+		return nil, ErrNotFound
+	}
+	before := cloneTask(t)
+	now := time.Now()
+	if err := fn(t, now); err != nil {
+		return nil, err
+	}
+	recordChanges(before, t, now)
+	t.UpdatedAt = now
+	t.Revision++
+	changes, err := formatChanges(t.Changes)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := formatTags(t.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.Exec(
+		`UPDATE tasks SET day = ?, date = ?, title = ?, notes = ?, tags = ?, priority = ?, project = ?, due = ?, updated_at = ?, completed_at = ?, revision = ?, deleted_at = ?, changes = ?
+		 WHERE id = ? AND revision = ?`,
+		dayKey(t.Date), formatTime(t.Date), t.Title, t.Notes, tags, int(t.Priority), t.Project, formatTimePtr(t.Due),
+		formatTime(t.UpdatedAt), formatTimePtr(t.CompletedAt), t.Revision, formatTimePtr(t.DeletedAt), changes,
+		t.ID, t.Revision-1,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("update task: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("update task: %w", err)
+	} else if n == 0 {
+		return nil, fmt.Errorf("task %d: %w", t.ID, ErrConflict)
+	}
+	return t, nil
+}
+
+// backupIfDue takes an automatic backup before a write when one is due.
+func (s *SQLiteStore) backupIfDue() error {
+	if s.auto == nil || !s.auto.due() {
+		return nil
+	}
+	if tasks := s.List(); len(tasks) > 0 {
+		if _, err := s.auto.backups.Create(tasks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertTx(c sqlConn, t *Task) error {
+	changes, err := formatChanges(t.Changes)
+	if err != nil {
+		return err
+	}
+	tags, err := formatTags(t.Tags)
+	if err != nil {
+		return err
+	}
+	_, err = c.Exec(
+		`INSERT INTO tasks (id, uuid, day, date, title, notes, due, created_at, updated_at, completed_at, revision, deleted_at, changes, tags, priority, project)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.ID, t.UUID, dayKey(t.Date), formatTime(t.Date), t.Title, t.Notes, formatTimePtr(t.Due),
+		formatTime(t.CreatedAt), formatTime(t.UpdatedAt), formatTimePtr(t.CompletedAt), max(t.Revision, 1),
+		formatTimePtr(t.DeletedAt), changes, tags, int(t.Priority), t.Project,
+	)
+	return err
+}
+
+// getTask returns the task with the given ID, whether it is in the trash or not.
+func getTask(c sqlConn, id int) (*Task, error) {
+	rows, err := c.Query(`SELECT `+sqliteColumns+` FROM tasks WHERE id = ?`, id)
+	if err != nil {
+		return nil, fmt.Errorf("query task: %w", err)
+	}
+	out, err := scanTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, ErrNotFound
+	}
+	return out[0], nil
+}
+
+// read runs a query for the methods of TaskStore that cannot fail. If it
+// does fail, it logs the error, keeps it for ReadError and returns no tasks.
+func (s sqliteReads) read(q string, args ...any) []*Task {
+	out, err := queryTasks(s.conn, q, args...)
+	if err != nil {
+		log.Printf("read %s: %v", s.path, err)
+		s.readErr.mu.Lock()
+		s.readErr.err = err
+		s.readErr.mu.Unlock()
+		return nil
+	}
+	return out
+}
+
+// ReadError returns the error of the last read that failed since the
+// previous call, if any, and forgets it.
+func (s *SQLiteStore) ReadError() error {
+	s.readErr.mu.Lock()
+	defer s.readErr.mu.Unlock()
+	err := s.readErr.err
+	s.readErr.err = nil
+	return err
+}
+
+func queryTasks(c sqlConn, q string, args ...any) ([]*Task, error) {
+	rows, err := c.Query(q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query tasks: %w", err)
+	}
+	return scanTasks(rows)
+}
+
+func scanTasks(rows *sql.Rows) ([]*Task, error) {
+	defer rows.Close()
+
+	var out []*Task
+	for rows.Next() {
+		var (
+			t                            Task
+			date, created, updated       string
+			uid, due, completed, deleted sql.NullString
+			changes, tags                sql.NullString
+		)
+		if err := rows.Scan(&t.ID, &uid, &date, &t.Title, &t.Notes, &due, &created, &updated, &completed, &t.Revision, &deleted, &changes, &tags, &t.Priority, &t.Project); err != nil {
+			return nil, fmt.Errorf("scan task: %w", err)
+		}
+		var err error
+		if t.Date, err = parseTime(date); err != nil {
+			return nil, err
+		}
+		if t.CreatedAt, err = parseTime(created); err != nil {
+			return nil, err
+		}
+		if t.UpdatedAt, err = parseTime(updated); err != nil {
+			return nil, err
+		}
+		if t.Due, err = parseTimePtr(due); err != nil {
+			return nil, err
+		}
+		if t.CompletedAt, err = parseTimePtr(completed); err != nil {
+			return nil, err
+		}
+		if t.DeletedAt, err = parseTimePtr(deleted); err != nil {
+			return nil, err
+		}
+		if t.Changes, err = parseChanges(changes); err != nil {
+			return nil, err
+		}
+		if t.Tags, err = parseTags(tags); err != nil {
+			return nil, err
+		}
+		// Rows added by an older version since the store was opened.
+		t.UUID = uid.String
+		if !uid.Valid {
+			t.UUID = legacyUUID(t.ID, t.CreatedAt)
+		}
+		out = append(out, &t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan tasks: %w", err)
+	}
+	return out, nil
+}
+
+// dayKey is the calendar day a task is filed under, in the task's own zone.
+func dayKey(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+func formatTimePtr(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return formatTime(*t)
+}
+
+func parseTime(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse time %q: %w", s, err)
+	}
+	return t, nil
+}
+
+func parseTimePtr(s sql.NullString) (*time.Time, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	t, err := parseTime(s.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
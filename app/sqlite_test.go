@@ -0,0 +1,129 @@
+package app
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestSQLiteUpgrade opens a database written before IDs were AUTOINCREMENT
+// and checks that its tasks are kept and the ID of a purged one is not
+// handed out again.
+func TestSQLiteUpgrade(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := len(sqliteMigrations) - 1
+	for i, stmt := range sqliteMigrations[:old] {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("migration %d: %v", i+1, err)
+		}
+	}
+	now := formatTime(time.Now())
+	for _, stmt := range []string{
+		fmt.Sprintf("PRAGMA user_version = %d", old),
+		`INSERT INTO tasks (id, uuid, day, date, title, created_at, updated_at, tags, priority, project)
+		 VALUES (1, 'u1', '2024-05-01', '` + now + `', 'kept', '` + now + `', '` + now + `', '["work"]', 3, 'home')`,
+		`INSERT INTO tasks (id, uuid, day, date, title, created_at, updated_at)
+		 VALUES (2, 'u2', '2024-05-01', '` + now + `', 'purged', '` + now + `', '` + now + `')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	s, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer s.Close()
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil || version != len(sqliteMigrations) {
+		t.Errorf("user_version = %d, %v, want %d", version, err, len(sqliteMigrations))
+	}
+	task, err := s.Get(1)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if task.Title != "kept" || task.UUID != "u1" || fmt.Sprint(task.Tags) != "[work]" || task.Priority != PriorityHigh || task.Project != "home" {
+		t.Errorf("Get() = %+v, want the task as it was", task)
+	}
+
+	if err := s.Delete(2); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := s.Purge(2); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	added, err := s.Add("new", "", nil, time.Now())
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if added.ID != 3 {
+		t.Errorf("Add() ID = %d, want 3", added.ID)
+	}
+}
+
+// TestSQLiteNextID checks that IDs keep growing across a reopen, after the
+// task with the highest one is purged.
+func TestSQLiteNextID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	s, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	a, err := s.Add("a", "", nil, time.Now())
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := s.Delete(a.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := s.Purge(a.ID); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	s.Close()
+
+	if s, err = OpenSQLite(path); err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer s.Close()
+	b, err := s.Add("b", "", nil, time.Now())
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if b.ID <= a.ID {
+		t.Errorf("ID after reopening = %d, want more than %d", b.ID, a.ID)
+	}
+}
+
+// TestSQLiteReadError checks that reads that fail return no tasks and
+// leave their error for ReadError.
+func TestSQLiteReadError(t *testing.T) {
+	s, err := OpenSQLite(filepath.Join(t.TempDir(), "tasks.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	if _, err := s.Add("a", "", nil, time.Now()); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := s.ReadError(); err != nil {
+		t.Errorf("ReadError() = %v, want nil", err)
+	}
+
+	s.db.Close()
+	if got := s.List(); len(got) != 0 {
+		t.Errorf("List() of a closed database = %v, want none", got)
+	}
+	if err := s.ReadError(); err == nil {
+		t.Errorf("ReadError() = nil, want the error of List")
+	}
+	if err := s.ReadError(); err != nil {
+		t.Errorf("ReadError() again = %v, want nil", err)
+	}
+}
@@ -0,0 +1,310 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Storage backends understood by Open.
+const (
+	BackendJSON     = "json"
+	BackendSQLite   = "sqlite"
+	BackendMarkdown = "markdown"
+)
+
+// TaskStore is the set of operations the UI and commands need from a task
+// storage backend. Implementations must be safe for concurrent use.
+type TaskStore interface {
+	List() []*Task
+	ListByDate(date time.Time) []*Task
+	// ListRange returns the tasks scheduled from the day of from through
+	// the day of to, by day and then sorted like List.
+	ListRange(from, to time.Time) []*Task
+	// Overdue returns the open tasks scheduled before the day of now, by
+	// priority, then due date (tasks without one last), then by ID.
+	Overdue(now time.Time) []*Task
+	// ListOpen returns the open tasks, whatever day they are scheduled
+	// for, sorted like Overdue.
+	ListOpen() []*Task
+	// CompletedOn returns the tasks completed on the day of day, whatever
+	// day they are scheduled for, sorted like List.
+	CompletedOn(day time.Time) []*Task
+	// ListProject returns the tasks in the project with the given path or
+	// one below it, sorted like List. An empty path stands for every
+	// project: it returns the tasks in any.
+	ListProject(path string) []*Task
+	Add(title, notes string, due *time.Time, date time.Time) (*Task, error)
+	Get(id int) (*Task, error)
+	Update(id int, opts UpdateOptions) (*Task, error)
+	MarkCompleted(id int, completed bool) (*Task, error)
+	ToggleCompleted(id int) (*Task, error)
+	Delete(id int) error
+	// Put stores a fully-formed task as given, keeping its ID and
+	// timestamps and replacing any task with the same ID. The stored
+	// revision is bumped past both the given and the replaced one.
+	Put(t *Task) (*Task, error)
+}
+
+// Importer is implemented by stores that can take fully-formed tasks,
+// keeping their IDs and timestamps. It is used to copy data between backends.
+type Importer interface {
+	Import(tasks []*Task) error
+}
+
+// Replacer is implemented by stores that can swap their whole contents,
+// e.g. to restore a backup.
+type Replacer interface {
+	ReplaceAll(tasks []*Task) error
+}
+
+// Trasher is implemented by stores whose Delete moves tasks to a trash,
+// from which they can be restored until they are purged.
+type Trasher interface {
+	// Trash returns the deleted tasks, most recently deleted first.
+	Trash() []*Task
+	// Restore moves a task out of the trash.
+	Restore(id int) (*Task, error)
+	// Purge removes a task from the trash for good.
+	Purge(id int) error
+	// PurgeTrash removes every task deleted at or before the given time
+	// and returns how many there were.
+	PurgeTrash(before time.Time) (int, error)
+}
+
+// Archiver is implemented by stores that move old completed tasks out of
+// the way into per-year archive files, which are only read on demand.
+type Archiver interface {
+	// Archive moves the tasks scheduled and completed before the given
+	// time into the archive and returns how many there were.
+	Archive(before time.Time) (int, error)
+	// ArchiveYears returns the years with archived tasks, newest first.
+	ArchiveYears() ([]int, error)
+	// Archived returns the archived tasks of a year, by day.
+	Archived(year int) ([]*Task, error)
+	// ArchivedByDate returns the archived tasks scheduled for the given day.
+	ArchivedByDate(date time.Time) ([]*Task, error)
+	// Unarchive moves a task out of the archive.
+	Unarchive(id int) (*Task, error)
+}
+
+// Batcher is implemented by stores that can make several changes at once.
+type Batcher interface {
+	// Tx runs fn with a view of the store in which its changes show up
+	// right away. They are saved together when fn returns nil, and
+	// dropped, leaving the store as it was, when it returns an error.
+	Tx(fn func(tx TaskStore) error) error
+}
+
+// Reloader is implemented by stores that cache tasks in memory. Reload
+// drops that cache, e.g. after an ErrConflict.
+type Reloader interface {
+	Reload() error
+}
+
+// ReadErrorer is implemented by stores whose reads can fail, like a
+// database that cannot be queried. The read methods of TaskStore return
+// no tasks then; ReadError tells why.
+type ReadErrorer interface {
+	// ReadError returns the error of the last read that failed since the
+	// previous call, if any, and forgets it.
+	ReadError() error
+}
+
+var _ ReadErrorer = (*SQLiteStore)(nil)
+
+var (
+	_ TaskStore = (*Store)(nil)
+	_ TaskStore = (*SQLiteStore)(nil)
+	_ TaskStore = (*MemoryStore)(nil)
+	_ TaskStore = (*MarkdownStore)(nil)
+	_ Importer  = (*MemoryStore)(nil)
+	_ Importer  = (*MarkdownStore)(nil)
+	_ Replacer  = (*Store)(nil)
+	_ Replacer  = (*SQLiteStore)(nil)
+	_ Replacer  = (*MemoryStore)(nil)
+	_ Replacer  = (*MarkdownStore)(nil)
+	_ Trasher   = (*Store)(nil)
+	_ Trasher   = (*SQLiteStore)(nil)
+	_ Trasher   = (*MemoryStore)(nil)
+	_ Trasher   = (*MarkdownStore)(nil)
+	_ Archiver  = (*Store)(nil)
+	_ Batcher   = (*Store)(nil)
+	_ Batcher   = (*MarkdownStore)(nil)
+	_ Batcher   = (*SQLiteStore)(nil)
+	_ TaskStore = (*storeTx)(nil)
+	_ TaskStore = (*sqliteTx)(nil)
+)
+
+// DefaultCompactEvery is how many journaled operations the JSON store
+// collects before folding them into its snapshot.
+const DefaultCompactEvery = 200
+
+// DefaultTrashRetention is how long deleted tasks stay in the trash.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// DefaultArchiveAfter is how long completed tasks stay in the JSON store
+// before they are archived, unless configured otherwise. Stores opened
+// without WithArchiveAfter never archive on their own.
+const DefaultArchiveAfter = 365 * 24 * time.Hour
+
+// Option configures a store opened with Open or Load.
+type Option func(*options)
+
+type options struct {
+	compactEvery int
+	backups      bool
+	backupDir    string
+	backupEvery  int
+	backupKeep   int
+
+	trashRetention time.Duration
+	archiveAfter   time.Duration
+	secret         *Secret
+	git            bool
+}
+
+func newOptions(opts []Option) options {
+	o := options{compactEvery: DefaultCompactEvery, trashRetention: DefaultTrashRetention}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// autoBackup returns the automatic backup schedule for the store at path,
+// or nil when backups are disabled.
+func (o options) autoBackup(path string) *autoBackup {
+	if !o.backups {
+		return nil
+	}
+	backups := NewBackups(path, o.backupDir, o.backupKeep)
+	backups.Secret = o.secret
+	return &autoBackup{
+		backups: backups,
+		every:   o.backupEvery,
+	}
+}
+
+// WithCompactEvery sets how many journaled operations trigger a compaction
+// of the JSON store. Zero or less rewrites the snapshot on every mutation.
+func WithCompactEvery(n int) Option {
+	return func(o *options) {
+		o.compactEvery = n
+	}
+}
+
+// WithBackups makes the store copy itself into dir (empty: "backups" next
+// to the store) before its first save in a session and then before every
+// nth save, keeping the newest keep backups.
+func WithBackups(dir string, every, keep int) Option {
+	return func(o *options) {
+		o.backups = true
+		o.backupDir = dir
+		o.backupEvery = every
+		o.backupKeep = keep
+	}
+}
+
+// Open returns the store for the given backend. An empty backend means JSON.
+func Open(backend, path string, opts ...Option) (TaskStore, error) {
+	switch backend {
+	case "", BackendJSON:
+		return Load(path, opts...)
+	case BackendSQLite:
+		return OpenSQLite(path, opts...)
+	case BackendMarkdown:
+		return OpenMarkdown(path, opts...)
+	default:
+		return nil, fmt.Errorf("unknown store backend %q", backend)
+	}
+}
+
+// DefaultPath returns the file a backend uses when none is configured:
+// tasks.json or tasks.db in $XDG_DATA_HOME/taskman, which defaults to
+// ~/.local/share/taskman.
+func DefaultPath(backend string) string {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, ".local", "share")
+		}
+	}
+	return filepath.Join(dir, "taskman", "tasks"+fileExt(backend))
+}
+
+// LegacyPath returns the file older versions kept a backend's tasks in,
+// relative to the working directory.
+func LegacyPath(backend string) string {
+	return "todo-tasks" + fileExt(backend)
+}
+
+// fileExt returns the extension of a backend's store. The markdown store
+// is a directory and has none.
+func fileExt(backend string) string {
+	switch backend {
+	case BackendSQLite:
+		return ".db"
+	case BackendMarkdown:
+		return ""
+	}
+	return ".json"
+}
+
+// MigratePath returns the file the store of backend from at path is copied
+// to by Migrate when it moves to backend to: the same file, next to it,
+// with the extension of the new backend, e.g. work.db for work.json.
+func MigratePath(path, from, to string) string {
+	return strings.TrimSuffix(path, fileExt(from)) + fileExt(to)
+}
+
+// Migrate copies every task from src into dst, keeping IDs and timestamps.
+// dst must be empty. It returns the number of copied tasks.
+func Migrate(src TaskStore, dst TaskStore) (int, error) {
+	imp, ok := dst.(Importer)
+	if !ok {
+		return 0, fmt.Errorf("destination store does not support import")
+	}
+	if n := len(dst.List()); n > 0 {
+		return 0, fmt.Errorf("destination store is not empty (%d tasks)", n)
+	}
+	tasks := src.List()
+	if err := imp.Import(tasks); err != nil {
+		return 0, err
+	}
+	return len(tasks), nil
+}
+
+// WithTrashRetention sets how long deleted tasks stay in the trash; older
+// ones are purged when the store is opened. Zero or less keeps them forever.
+func WithTrashRetention(d time.Duration) Option {
+	return func(o *options) {
+		o.trashRetention = d
+	}
+}
+
+// WithSecret encrypts the JSON store with secret. Stores written in plain
+// JSON are still read; they are encrypted on their next compaction.
+func WithSecret(secret *Secret) Option {
+	return func(o *options) {
+		o.secret = secret
+	}
+}
+
+// WithGit keeps the directory of the JSON store under git and commits the
+// snapshot after every change. See Store.Sync.
+func WithGit() Option {
+	return func(o *options) {
+		o.git = true
+	}
+}
+
+// WithArchiveAfter moves tasks completed more than d ago into the archive
+// when the JSON store is opened. Zero or less never archives on its own.
+func WithArchiveAfter(d time.Duration) Option {
+	return func(o *options) {
+		o.archiveAfter = d
+	}
+}
@@ -0,0 +1,159 @@
+package storetest
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"taskman/app"
+)
+
+// RunSaved runs the checks that what a store saves is read back the same
+// after it is closed and opened again. open must open the store kept in
+// dir, creating it the first time; RunSaved closes every store it opens.
+func RunSaved(t *testing.T, open func(t *testing.T, dir string) app.TaskStore) {
+	for _, tc := range []struct {
+		name string
+		fn   func(t *testing.T, s app.TaskStore, reopen func() app.TaskStore)
+	}{
+		{"SavedChanges", savedChanges},
+		{"SavedTags", savedTags},
+		{"SavedPriority", savedPriority},
+		{"SavedProject", savedProject},
+		{"SavedIDs", savedIDs},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s := open(t, dir)
+			t.Cleanup(func() { closeStore(t, s) })
+			tc.fn(t, s, func() app.TaskStore {
+				t.Helper()
+				closeStore(t, s)
+				s = nil // not closed again if open fails
+				s = open(t, dir)
+				return s
+			})
+		})
+	}
+}
+
+// closeStore closes s if it has anything to close.
+func closeStore(t *testing.T, s app.TaskStore) {
+	t.Helper()
+	if c, ok := s.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+	}
+}
+
+// get returns the task with the given ID from s.
+func get(t *testing.T, s app.TaskStore, id int) *app.Task {
+	t.Helper()
+	task, err := s.Get(id)
+	if err != nil {
+		t.Fatalf("Get(%d) error = %v", id, err)
+	}
+	return task
+}
+
+// savedChanges checks that the history of a task, with titles that need
+// quoting and a trip through the trash, is read back in full.
+func savedChanges(t *testing.T, s app.TaskStore, reopen func() app.TaskStore) {
+	a := add(t, s, "a", nil, day)
+	title := `a → "b"`
+	if _, err := s.Update(a.ID, app.UpdateOptions{Title: &title}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := s.Delete(a.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.(app.Trasher).Restore(a.ID); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	got := get(t, reopen(), a.ID)
+	var fields []string
+	for _, c := range got.Changes {
+		fields = append(fields, c.Field)
+	}
+	if want := "[title deleted deleted]"; fmt.Sprint(fields) != want {
+		t.Fatalf("history after reopening = %v, want changes of %s", got.Changes, want)
+	}
+	if c := got.Changes[0]; c.Old != `"a"` || c.New != `"a → \"b\""` {
+		t.Errorf("title change = %+v", c)
+	}
+	if c := got.Changes[2]; c.New != "none" {
+		t.Errorf("restore = %+v, want deleted cleared", c)
+	}
+}
+
+// savedTags checks that tags are read back in order, and that a task
+// without any has none rather than an empty list.
+func savedTags(t *testing.T, s app.TaskStore, reopen func() app.TaskStore) {
+	a := add(t, s, "a", nil, day)
+	b := add(t, s, "b", nil, day)
+	tags := []string{"work", "home"}
+	if _, err := s.Update(a.ID, app.UpdateOptions{Tags: &tags}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	s = reopen()
+	if got := get(t, s, a.ID); fmt.Sprint(got.Tags) != "[work home]" {
+		t.Errorf("tags after reopening = %v, want [work home]", got.Tags)
+	}
+	if got := get(t, s, b.ID); got.Tags != nil {
+		t.Errorf("tags of an untagged task = %#v, want nil", got.Tags)
+	}
+	if got := fmt.Sprint(app.CountTags(s.List())); got != "[{home 1} {work 1}]" {
+		t.Errorf("CountTags() = %s", got)
+	}
+}
+
+// savedPriority checks that the priority of a task is read back.
+func savedPriority(t *testing.T, s app.TaskStore, reopen func() app.TaskStore) {
+	a := add(t, s, "a", nil, day)
+	p := app.PriorityCritical
+	if _, err := s.Update(a.ID, app.UpdateOptions{Priority: &p}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	if got := get(t, reopen(), a.ID); got.Priority != app.PriorityCritical {
+		t.Errorf("priority after reopening = %v, want critical", got.Priority)
+	}
+}
+
+// savedProject checks that the project of a task is read back normalized,
+// with the move into it in the history.
+func savedProject(t *testing.T, s app.TaskStore, reopen func() app.TaskStore) {
+	a := add(t, s, "a", nil, day)
+	project := "Work.Backend"
+	if _, err := s.Update(a.ID, app.UpdateOptions{Project: &project}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	got := get(t, reopen(), a.ID)
+	if got.Project != "work.backend" {
+		t.Errorf("project after reopening = %q, want work.backend", got.Project)
+	}
+	if n := len(got.Changes); n != 1 || got.Changes[0].New != "work.backend" {
+		t.Errorf("history = %v, want the move to work.backend", got.Changes)
+	}
+}
+
+// savedIDs checks that the ID of a purged task is not handed out again
+// after reopening, not even when it was the highest one.
+func savedIDs(t *testing.T, s app.TaskStore, reopen func() app.TaskStore) {
+	add(t, s, "a", nil, day)
+	b := add(t, s, "b", nil, day)
+	if err := s.Delete(b.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := s.(app.Trasher).Purge(b.ID); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+
+	if c := add(t, reopen(), "c", nil, day); c.ID <= b.ID {
+		t.Errorf("ID after purging %d and reopening = %d, want a new one", b.ID, c.ID)
+	}
+}
@@ -0,0 +1,485 @@
+// Package storetest checks that an app.TaskStore behaves the way the UI
+// and the commands expect of every backend. A backend runs it from its
+// tests:
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) app.TaskStore {
+//			return app.NewMemoryStore()
+//		})
+//	}
+//
+// Backends that keep their tasks on disk run RunSaved as well.
+package storetest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"taskman/app"
+)
+
+// Run runs the conformance suite. open must return a new, empty store on
+// every call; closing it is up to open, e.g. with t.Cleanup.
+func Run(t *testing.T, open func(t *testing.T) app.TaskStore) {
+	for _, tc := range []struct {
+		name string
+		fn   func(t *testing.T, s app.TaskStore)
+	}{
+		{"IDs", testIDs},
+		{"Sorting", testSorting},
+		{"Priority", testPriority},
+		{"ListByDate", testListByDate},
+		{"Completion", testCompletion},
+		{"UpdateOptions", testUpdateOptions},
+		{"History", testHistory},
+		{"Undo", testUndo},
+		{"Tags", testTags},
+		{"Project", testProject},
+		{"Errors", testErrors},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, open(t))
+		})
+	}
+}
+
+var day = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+func add(t *testing.T, s app.TaskStore, title string, due *time.Time, date time.Time) *app.Task {
+	t.Helper()
+	task, err := s.Add(title, "", due, date)
+	if err != nil {
+		t.Fatalf("Add(%q) error = %v", title, err)
+	}
+	return task
+}
+
+func at(h int) *time.Time {
+	v := day.Add(time.Duration(h) * time.Hour)
+	return &v
+}
+
+func titles(tasks []*app.Task) string {
+	out := make([]string, len(tasks))
+	for i, t := range tasks {
+		out[i] = t.Title
+	}
+	return fmt.Sprint(out)
+}
+
+// testIDs checks that IDs are positive, increasing and never reused.
+func testIDs(t *testing.T, s app.TaskStore) {
+	a := add(t, s, "a", nil, day)
+	b := add(t, s, "b", nil, day)
+	if a.ID <= 0 || b.ID <= a.ID {
+		t.Errorf("IDs = %d, %d, want positive and increasing", a.ID, b.ID)
+	}
+	if err := s.Delete(b.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if c := add(t, s, "c", nil, day); c.ID <= b.ID {
+		t.Errorf("ID after deleting %d = %d, want a new one", b.ID, c.ID)
+	}
+
+	put, err := s.Put(&app.Task{ID: 100, Date: day, Title: "put", CreatedAt: day, UpdatedAt: day})
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if put.ID != 100 {
+		t.Errorf("Put() ID = %d, want 100", put.ID)
+	}
+	d := add(t, s, "d", nil, day)
+	if d.ID <= 100 {
+		t.Errorf("ID after Put() of 100 = %d, want a higher one", d.ID)
+	}
+
+	// Not even the ID of a purged task comes back.
+	if trash, ok := s.(app.Trasher); ok {
+		if err := s.Delete(d.ID); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if err := trash.Purge(d.ID); err != nil {
+			t.Fatalf("Purge() error = %v", err)
+		}
+		if e := add(t, s, "e", nil, day); e.ID <= d.ID {
+			t.Errorf("ID after purging %d = %d, want a new one", d.ID, e.ID)
+		}
+	}
+}
+
+// testSorting checks the order of List: open tasks by due date, those
+// without one last, then completed tasks newest first, ties by ID.
+func testSorting(t *testing.T, s app.TaskStore) {
+	add(t, s, "no due 1", nil, day)
+	late := add(t, s, "due late", at(30), day)
+	add(t, s, "no due 2", nil, day)
+	add(t, s, "due early", at(20), day)
+	older := add(t, s, "done older", nil, day)
+	newer := add(t, s, "done newer", at(10), day)
+
+	for _, c := range []struct {
+		task *app.Task
+		at   *time.Time
+	}{{older, at(1)}, {newer, at(2)}} {
+		c.task.CompletedAt = c.at
+		if _, err := s.Put(c.task); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+	want := "[due early due late no due 1 no due 2 done newer done older]"
+	if got := titles(s.List()); got != want {
+		t.Errorf("List() = %s, want %s", got, want)
+	}
+
+	// Equal due dates fall back to the ID.
+	add(t, s, "due late too", late.Due, day)
+	want = "[due early due late due late too no due 1 no due 2 done newer done older]"
+	if got := titles(s.List()); got != want {
+		t.Errorf("List() = %s, want %s", got, want)
+	}
+}
+
+// testPriority checks that open tasks are sorted by priority before their
+// due date, in List, Overdue and ListOpen, and that completed ones are not.
+func testPriority(t *testing.T, s app.TaskStore) {
+	add(t, s, "due", at(1), day)
+	add(t, s, "later", nil, day.AddDate(0, 0, 7))
+	low := add(t, s, "low", nil, day)
+	high := add(t, s, "high", at(5), day)
+	done := add(t, s, "done high", nil, day)
+	for task, p := range map[*app.Task]app.Priority{low: app.PriorityLow, high: app.PriorityHigh, done: app.PriorityHigh} {
+		if _, err := s.Update(task.ID, app.UpdateOptions{Priority: &p}); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+	}
+	if _, err := s.MarkCompleted(done.ID, true); err != nil {
+		t.Fatalf("MarkCompleted() error = %v", err)
+	}
+
+	if got, want := titles(s.List()), "[high low due later done high]"; got != want {
+		t.Errorf("List() = %s, want %s", got, want)
+	}
+	if got, want := titles(s.Overdue(day.AddDate(0, 0, 1))), "[high low due]"; got != want {
+		t.Errorf("Overdue() = %s, want %s", got, want)
+	}
+	if got, want := titles(s.ListOpen()), "[high low due later]"; got != want {
+		t.Errorf("ListOpen() = %s, want %s", got, want)
+	}
+	if got, _ := s.Get(high.ID); got.Priority != app.PriorityHigh {
+		t.Errorf("Priority = %v, want high", got.Priority)
+	}
+
+	invalid := app.PriorityCritical + 1
+	if _, err := s.Update(low.ID, app.UpdateOptions{Priority: &invalid}); err == nil {
+		t.Error("Update() with an invalid priority succeeded")
+	}
+}
+
+// testListByDate checks that ListByDate only returns the tasks of its
+// day, sorted like List.
+func testListByDate(t *testing.T, s app.TaskStore) {
+	next := day.AddDate(0, 0, 1)
+	add(t, s, "b", nil, day)
+	add(t, s, "tomorrow", nil, next)
+	add(t, s, "a", at(5), day.Add(9*time.Hour))
+
+	if got, want := titles(s.ListByDate(day)), "[a b]"; got != want {
+		t.Errorf("ListByDate(day) = %s, want %s", got, want)
+	}
+	if got, want := titles(s.ListByDate(next)), "[tomorrow]"; got != want {
+		t.Errorf("ListByDate(next day) = %s, want %s", got, want)
+	}
+	if got := s.ListByDate(day.AddDate(0, 0, -1)); len(got) != 0 {
+		t.Errorf("ListByDate(day before) = %s, want none", titles(got))
+	}
+}
+
+// testCompletion checks ToggleCompleted and MarkCompleted.
+func testCompletion(t *testing.T, s app.TaskStore) {
+	task := add(t, s, "a", nil, day)
+	if task.IsCompleted() {
+		t.Fatalf("new task is completed")
+	}
+
+	done, err := s.ToggleCompleted(task.ID)
+	if err != nil {
+		t.Fatalf("ToggleCompleted() error = %v", err)
+	}
+	if !done.IsCompleted() || done.Revision <= task.Revision || done.UpdatedAt.Before(task.UpdatedAt) {
+		t.Errorf("ToggleCompleted() = %+v, want completed at a new revision", done)
+	}
+	if got, _ := s.Get(task.ID); !got.IsCompleted() {
+		t.Errorf("Get() after ToggleCompleted() is not completed")
+	}
+
+	// Marking a completed task completed keeps its completion time.
+	again, err := s.MarkCompleted(task.ID, true)
+	if err != nil {
+		t.Fatalf("MarkCompleted(true) error = %v", err)
+	}
+	if !again.CompletedAt.Equal(*done.CompletedAt) {
+		t.Errorf("MarkCompleted(true) moved CompletedAt from %v to %v", done.CompletedAt, again.CompletedAt)
+	}
+
+	open, err := s.ToggleCompleted(task.ID)
+	if err != nil {
+		t.Fatalf("ToggleCompleted() error = %v", err)
+	}
+	if open.IsCompleted() {
+		t.Errorf("ToggleCompleted() twice = %+v, want open again", open)
+	}
+	s.MarkCompleted(task.ID, true)
+	if got, _ := s.MarkCompleted(task.ID, false); got.IsCompleted() {
+		t.Errorf("MarkCompleted(false) = %+v, want open", got)
+	}
+
+	// CompletedOn finds tasks by the day they were completed, not the day
+	// they are scheduled for.
+	other := add(t, s, "b", nil, day.AddDate(0, 0, -3))
+	done, err = s.MarkCompleted(other.ID, true)
+	if err != nil {
+		t.Fatalf("MarkCompleted(true) error = %v", err)
+	}
+	if got := titles(s.CompletedOn(*done.CompletedAt)); got != "[b]" {
+		t.Errorf("CompletedOn() = %s, want [b]", got)
+	}
+	if got := s.CompletedOn(day); len(got) != 0 {
+		t.Errorf("CompletedOn() of the scheduled day = %s, want none", titles(got))
+	}
+	s.MarkCompleted(other.ID, false)
+	if got := s.CompletedOn(*done.CompletedAt); len(got) != 0 {
+		t.Errorf("CompletedOn() after reopening = %s, want none", titles(got))
+	}
+}
+
+// testUpdateOptions checks that nil options leave fields alone and that a
+// pointer to nil clears the due date.
+func testUpdateOptions(t *testing.T, s app.TaskStore) {
+	task, err := s.Add("title", "notes", at(5), day)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	same, err := s.Update(task.ID, app.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if same.Title != "title" || same.Notes != "notes" || same.Due == nil || !same.Due.Equal(*at(5)) || !same.Date.Equal(day) {
+		t.Errorf("Update() with no options = %+v, want nothing changed", same)
+	}
+
+	title, notes := "new title", ""
+	got, err := s.Update(task.ID, app.UpdateOptions{Title: &title, Notes: &notes})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got.Title != title || got.Notes != "" || got.Due == nil {
+		t.Errorf("Update() of title and notes = %+v, want new title, no notes and the due date kept", got)
+	}
+
+	due := at(8)
+	if got, _ = s.Update(task.ID, app.UpdateOptions{Due: &due}); got.Due == nil || !got.Due.Equal(*due) {
+		t.Errorf("Update() of due = %v, want %v", got.Due, due)
+	}
+	var none *time.Time
+	if got, _ = s.Update(task.ID, app.UpdateOptions{Due: &none}); got.Due != nil {
+		t.Errorf("Update() clearing due = %v, want nil", got.Due)
+	}
+
+	next := day.AddDate(0, 0, 1)
+	if got, _ = s.Update(task.ID, app.UpdateOptions{Date: &next}); !got.Date.Equal(next) {
+		t.Errorf("Update() of date = %v, want %v", got.Date, next)
+	}
+	if n := len(s.ListByDate(day)); n != 0 {
+		t.Errorf("len(ListByDate(old day)) = %d, want 0", n)
+	}
+	if got, _ := s.Get(task.ID); got.Title != title || got.Due != nil || !got.Date.Equal(next) {
+		t.Errorf("Get() = %+v, want every update saved", got)
+	}
+}
+
+// testHistory checks that updates and completion are recorded in the
+// history of the task, field by field and oldest first.
+func testHistory(t *testing.T, s app.TaskStore) {
+	task := add(t, s, "draft", nil, day)
+	if len(task.Changes) != 0 {
+		t.Errorf("history of a new task = %v, want none", task.Changes)
+	}
+
+	title, due := "report", at(5)
+	if _, err := s.Update(task.ID, app.UpdateOptions{Title: &title, Due: &due}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	s.Update(task.ID, app.UpdateOptions{Title: &title}) // no change
+	if _, err := s.ToggleCompleted(task.ID); err != nil {
+		t.Fatalf("ToggleCompleted() error = %v", err)
+	}
+
+	got, _ := s.Get(task.ID)
+	var fields []string
+	for _, c := range got.Changes {
+		fields = append(fields, c.Field)
+	}
+	if want := "[title due completed]"; fmt.Sprint(fields) != want {
+		t.Fatalf("history = %v, want changes of %s", got.Changes, want)
+	}
+	if c := got.Changes[0]; c.Old != `"draft"` || c.New != `"report"` || c.At.Before(task.CreatedAt) {
+		t.Errorf("title change = %+v, want \"draft\" to \"report\" after the task was created", c)
+	}
+	if c := got.Changes[1]; c.Old != "none" || c.New == "none" {
+		t.Errorf("due change = %+v, want from none to a time", c)
+	}
+}
+
+// testUndo checks that undoing and redoing through an app.History adds to
+// the history of the task instead of rolling it back.
+func testUndo(t *testing.T, s app.TaskStore) {
+	h := app.NewHistory(s, 0)
+	task, err := h.Add("draft", "", nil, day)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	title := "report"
+	if _, err := h.Update(task.ID, app.UpdateOptions{Title: &title}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := h.Undo(); err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	if _, err := h.Redo(); err != nil {
+		t.Fatalf("Redo() error = %v", err)
+	}
+	if err := h.Delete(task.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := h.Undo(); err != nil {
+		t.Fatalf("Undo() of Delete() error = %v", err)
+	}
+
+	got, err := s.Get(task.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	var fields []string
+	for _, c := range got.Changes {
+		fields = append(fields, c.Field+" "+c.New)
+	}
+	if want := `[title "report" title "draft" title "report" deleted`; !strings.HasPrefix(fmt.Sprint(fields), want) || len(fields) != 5 {
+		t.Errorf("history = %v, want the edit, its undo and redo, the delete and its undo", fields)
+	}
+	if got.Title != title || got.DeletedAt != nil {
+		t.Errorf("task = %+v, want %q back from the trash", got, title)
+	}
+}
+
+// testTags checks that tags are normalized, saved and cleared by Update.
+func testTags(t *testing.T, s app.TaskStore) {
+	task := add(t, s, "a", nil, day)
+	tags := []string{"Work", "#home", "work"}
+	if _, err := s.Update(task.ID, app.UpdateOptions{Tags: &tags}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	got, _ := s.Get(task.ID)
+	if fmt.Sprint(got.Tags) != "[work home]" || !got.HasTag("home") {
+		t.Errorf("tags = %v, want [work home]", got.Tags)
+	}
+	if n := len(got.Changes); n != 1 || got.Changes[0].Field != "tags" {
+		t.Errorf("history = %v, want the tags change", got.Changes)
+	}
+
+	tags = nil
+	s.Update(task.ID, app.UpdateOptions{Tags: &tags})
+	if got, _ := s.Get(task.ID); len(got.Tags) != 0 {
+		t.Errorf("tags after clearing = %v, want none", got.Tags)
+	}
+}
+
+// testProject checks that projects are normalized, saved and cleared by
+// Update.
+func testProject(t *testing.T, s app.TaskStore) {
+	task := add(t, s, "a", nil, day)
+	project := " Work. Backend "
+	if _, err := s.Update(task.ID, app.UpdateOptions{Project: &project}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got, _ := s.Get(task.ID); got.Project != "work.backend" || !got.InProject("work") {
+		t.Errorf("project = %q, want work.backend", got.Project)
+	}
+
+	// ListProject takes in subprojects, but not projects that only start
+	// with the same letters.
+	for _, tc := range []struct{ title, project string }{{"b", "work"}, {"c", "workshop"}, {"d", "home"}} {
+		b := add(t, s, tc.title, nil, day)
+		if _, err := s.Update(b.ID, app.UpdateOptions{Project: &tc.project}); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+	}
+	add(t, s, "e", nil, day)
+	for path, want := range map[string]string{
+		"work":         "[a b]",
+		"work.backend": "[a]",
+		"work.b":       "[]",
+		"":             "[a b c d]",
+	} {
+		if got := titles(s.ListProject(path)); got != want {
+			t.Errorf("ListProject(%q) = %s, want %s", path, got, want)
+		}
+	}
+
+	project = ""
+	s.Update(task.ID, app.UpdateOptions{Project: &project})
+	if got, _ := s.Get(task.ID); got.Project != "" {
+		t.Errorf("project after clearing = %q, want none", got.Project)
+	}
+	if got := titles(s.ListProject("work")); got != "[b]" {
+		t.Errorf("ListProject() after clearing = %s, want [b]", got)
+	}
+}
+
+// testErrors checks the error values of the store.
+func testErrors(t *testing.T, s app.TaskStore) {
+	const missing = 999
+	title := "x"
+	for name, fn := range map[string]func() error{
+		"Get":             func() error { _, err := s.Get(missing); return err },
+		"Update":          func() error { _, err := s.Update(missing, app.UpdateOptions{Title: &title}); return err },
+		"MarkCompleted":   func() error { _, err := s.MarkCompleted(missing, true); return err },
+		"ToggleCompleted": func() error { _, err := s.ToggleCompleted(missing); return err },
+		"Delete":          func() error { return s.Delete(missing) },
+	} {
+		if err := fn(); !errors.Is(err, app.ErrNotFound) {
+			t.Errorf("%s() of a missing task error = %v, want ErrNotFound", name, err)
+		}
+	}
+
+	if _, err := s.Add("", "", nil, day); !errors.Is(err, app.ErrTitleRequired) {
+		t.Errorf("Add() without title error = %v, want ErrTitleRequired", err)
+	}
+	if _, err := s.Put(&app.Task{ID: 50, Date: day}); !errors.Is(err, app.ErrTitleRequired) {
+		t.Errorf("Put() without title error = %v, want ErrTitleRequired", err)
+	}
+	task := add(t, s, "a", nil, day)
+	empty := ""
+	if _, err := s.Update(task.ID, app.UpdateOptions{Title: &empty}); !errors.Is(err, app.ErrTitleRequired) {
+		t.Errorf("Update() to an empty title error = %v, want ErrTitleRequired", err)
+	}
+	if got, _ := s.Get(task.ID); got.Title != "a" || got.Revision != task.Revision {
+		t.Errorf("task after failed Update() = %+v, want it unchanged", got)
+	}
+	if len(s.List()) != 1 {
+		t.Errorf("len(List()) = %d after failed adds, want 1", len(s.List()))
+	}
+
+	if err := s.Delete(task.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.Get(task.ID); !errors.Is(err, app.ErrNotFound) {
+		t.Errorf("Get() of a deleted task error = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(task.ID); !errors.Is(err, app.ErrNotFound) {
+		t.Errorf("Delete() twice error = %v, want ErrNotFound", err)
+	}
+}
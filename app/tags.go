@@ -0,0 +1,98 @@
+package app
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Tags are short lowercase labels, kept on a task in the order they were
+// given. "#Work" and "work" are the same tag.
+
+// ParseTags reads tags as typed by the user, separated by commas or
+// spaces, e.g. "work, #errands home".
+func ParseTags(s string) []string {
+	return NormalizeTags(strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	}))
+}
+
+// NormalizeTags returns tags lowercased, without a leading '#', empty
+// ones and repeats; nil if none are left.
+func NormalizeTags(tags []string) []string {
+	var out []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+		if tag != "" && !slices.Contains(out, tag) {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+// HasTag reports whether the task carries tag.
+func (t *Task) HasTag(tag string) bool {
+	return slices.Contains(t.Tags, tag)
+}
+
+// TagCount is a tag and the number of tasks carrying it.
+type TagCount struct {
+	Tag   string
+	Tasks int
+}
+
+// CountTags returns the tags of tasks, sorted by name.
+func CountTags(tasks []*Task) []TagCount {
+	counts := make(map[string]int)
+	for _, t := range tasks {
+		for _, tag := range t.Tags {
+			counts[tag]++
+		}
+	}
+	out := make([]TagCount, 0, len(counts))
+	for tag, n := range counts {
+		out = append(out, TagCount{Tag: tag, Tasks: n})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Tag < out[j].Tag })
+	return out
+}
+
+// showTags formats tags for the history and merge reports.
+func showTags(v any) string {
+	tags := v.([]string)
+	if len(tags) == 0 {
+		return "none"
+	}
+	return "#" + strings.Join(tags, " #")
+}
+
+func equalTags(a, b any) bool {
+	return slices.Equal(a.([]string), b.([]string))
+}
+
+// formatTags and parseTags keep the tags of a task in a column of the
+// SQLite store, as JSON; NULL when there are none.
+func formatTags(tags []string) (any, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(tags)
+	if err != nil {
+		return nil, fmt.Errorf("encode tags: %w", err)
+	}
+	return string(data), nil
+}
+
+func parseTags(s sql.NullString) ([]string, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	var tags []string
+	if err := json.Unmarshal([]byte(s.String), &tags); err != nil {
+		return nil, fmt.Errorf("decode tags: %w", err)
+	}
+	return tags, nil
+}
@@ -0,0 +1,19 @@
+package app
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	for in, want := range map[string]string{
+		"":                      "[]",
+		"work":                  "[work]",
+		"Work, #errands  home,": "[work errands home]",
+		"a a #A":                "[a]",
+	} {
+		if got := fmt.Sprint(ParseTags(in)); got != want {
+			t.Errorf("ParseTags(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Trash returns the deleted tasks, most recently deleted first.
+func (s *Store) Trash() []*Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*Task, len(s.trash))
+	copy(out, s.trash)
+
+	sortTrash(out)
+	return out
+}
+
+// Restore moves a task out of the trash and saves it.
+func (s *Store) Restore(id int) (*Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := s.findTrashUnsafe(id)
+	if seen == nil {
+		return nil, ErrNotFound
+	}
+	if err := s.beginUnsafe(); err != nil {
+		return nil, err
+	}
+	defer s.unlockUnsafe()
+
+	cur := s.findTrashUnsafe(id)
+	if cur == nil || cur.Revision != seen.Revision {
+		return nil, fmt.Errorf("task %d: %w", id, ErrConflict)
+	}
+	t := cloneTask(cur)
+	now := time.Now()
+	t.DeletedAt = nil
+	recordChanges(cur, t, now)
+	t.UpdatedAt = now
+	t.Revision++
+
+	if err := s.commitUnsafe(journalEntry{Op: opRestore, At: now, Task: t}); err != nil {
+		return nil, err
+	}
+	return cloneTask(t), nil
+}
+
+// Purge removes a task from the trash for good.
+func (s *Store) Purge(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.beginUnsafe(); err != nil {
+		return err
+	}
+	defer s.unlockUnsafe()
+
+	if s.findTrashUnsafe(id) == nil {
+		return ErrNotFound
+	}
+	return s.commitUnsafe(journalEntry{Op: opPurge, At: time.Now(), ID: id})
+}
+
+// PurgeTrash removes every task deleted at or before the given time.
+func (s *Store) PurgeTrash(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.beginUnsafe(); err != nil {
+		return 0, err
+	}
+	defer s.unlockUnsafe()
+	return s.purgeTrashUnsafe(before)
+}
+
+func (s *Store) purgeTrashUnsafe(before time.Time) (int, error) {
+	now := time.Now()
+	var entries []journalEntry
+	for _, t := range s.trash {
+		if expired(t, before) {
+			entries = append(entries, journalEntry{Op: opPurge, At: now, ID: t.ID})
+		}
+	}
+	if err := s.commitUnsafe(entries...); err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+func (s *Store) findTrashUnsafe(id int) *Task {
+	for _, t := range s.trash {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// Trash returns the deleted tasks, most recently deleted first.
+func (s *SQLiteStore) Trash() []*Task {
+	out := s.read(`SELECT ` + sqliteColumns + ` FROM tasks WHERE deleted_at IS NOT NULL`)
+	sortTrash(out)
+	return out
+}
+
+// Restore moves a task out of the trash.
+func (s *SQLiteStore) Restore(id int) (*Task, error) {
+	return s.modify(id, true, opRestore, func(t *Task, now time.Time) error {
+		t.DeletedAt = nil
+		return nil
+	})
+}
+
+// Purge removes a task from the trash for good.
+func (s *SQLiteStore) Purge(id int) error {
+	res, err := s.db.Exec(`DELETE FROM tasks WHERE id = ? AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		return fmt.Errorf("purge task: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("purge task: %w", err)
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// PurgeTrash removes every task deleted at or before the given time, in a
+// single transaction.
+func (s *SQLiteStore) PurgeTrash(before time.Time) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin purge: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Times are stored as text in their own zone, so compare them in Go.
+	rows, err := tx.Query(`SELECT ` + sqliteColumns + ` FROM tasks WHERE deleted_at IS NOT NULL`)
+	if err != nil {
+		return 0, fmt.Errorf("query trash: %w", err)
+	}
+	trash, err := scanTasks(rows)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, t := range trash {
+		if !expired(t, before) {
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM tasks WHERE id = ?`, t.ID); err != nil {
+			return 0, fmt.Errorf("purge task %d: %w", t.ID, err)
+		}
+		n++
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit purge: %w", err)
+	}
+	return n, nil
+}
+
+// expired reports whether a trashed task was deleted at or before the given time.
+func expired(t *Task, before time.Time) bool {
+	return t.DeletedAt != nil && !t.DeletedAt.After(before)
+}
+
+// sortTrash orders trashed tasks by deletion time, newest first, then by ID.
+func sortTrash(out []*Task) {
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.DeletedAt != nil && b.DeletedAt != nil && !a.DeletedAt.Equal(*b.DeletedAt) {
+			return b.DeletedAt.Before(*a.DeletedAt)
+		}
+		return a.ID < b.ID
+	})
+}
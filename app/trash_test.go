@@ -0,0 +1,111 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTrash(t *testing.T) {
+	for _, backend := range []string{BackendJSON, BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			store, err := Open(backend, filepath.Join(t.TempDir(), "tasks"+fileExt(backend)))
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			t.Cleanup(func() { store.(io.Closer).Close() })
+			trash := store.(Trasher)
+			day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+			a, _ := store.Add("write report", "", nil, day)
+			b, _ := store.Add("call bob", "", nil, day)
+			if err := store.Delete(a.ID); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if _, err := store.Get(a.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() of trashed task error = %v, want ErrNotFound", err)
+			}
+			if got := len(store.ListByDate(day)); got != 1 {
+				t.Errorf("len(ListByDate()) = %d, want 1", got)
+			}
+			if got := trash.Trash(); len(got) != 1 || got[0].ID != a.ID || got[0].DeletedAt == nil {
+				t.Fatalf("Trash() = %+v, want task %d", got, a.ID)
+			}
+
+			restored, err := trash.Restore(a.ID)
+			if err != nil {
+				t.Fatalf("Restore() error = %v", err)
+			}
+			if restored.DeletedAt != nil || !restored.CreatedAt.Equal(a.CreatedAt) {
+				t.Errorf("Restore() = %+v, want live task created at %v", restored, a.CreatedAt)
+			}
+			if _, err := store.Get(a.ID); err != nil {
+				t.Errorf("Get() after restore error = %v", err)
+			}
+
+			store.Delete(a.ID)
+			store.Delete(b.ID)
+			if err := trash.Purge(a.ID); err != nil {
+				t.Fatalf("Purge() error = %v", err)
+			}
+			if err := trash.Purge(a.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("second Purge() error = %v, want ErrNotFound", err)
+			}
+			if n, err := trash.PurgeTrash(time.Now()); err != nil || n != 1 {
+				t.Errorf("PurgeTrash() = %d, %v, want 1", n, err)
+			}
+			if got := trash.Trash(); len(got) != 0 {
+				t.Errorf("Trash() after purge = %+v, want empty", got)
+			}
+		})
+	}
+}
+
+func TestTrashRetention(t *testing.T) {
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	recent := time.Now().Add(-time.Hour)
+	tasks := []*Task{
+		{ID: 1, Title: "old", CreatedAt: old, UpdatedAt: old, DeletedAt: &old, Revision: 2},
+		{ID: 2, Title: "recent", CreatedAt: old, UpdatedAt: recent, DeletedAt: &recent, Revision: 2},
+	}
+
+	t.Run(BackendJSON, func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tasks.json")
+		data, _ := snapshot{Version: CurrentVersion, Trash: tasks}.MarshalJSON()
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		s, err := Load(path, WithTrashRetention(24*time.Hour))
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		defer s.Close()
+		if got := s.Trash(); len(got) != 1 || got[0].ID != 2 {
+			t.Errorf("Trash() = %+v, want only task 2", got)
+		}
+		if s.NextID != 3 {
+			t.Errorf("NextID = %d, want 3", s.NextID)
+		}
+	})
+
+	t.Run(BackendSQLite, func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tasks.db")
+		s, _ := OpenSQLite(path, WithTrashRetention(0))
+		if err := s.Import(tasks); err != nil {
+			t.Fatalf("Import() error = %v", err)
+		}
+		s.Close()
+
+		s, err := OpenSQLite(path, WithTrashRetention(24*time.Hour))
+		if err != nil {
+			t.Fatalf("OpenSQLite() error = %v", err)
+		}
+		defer s.Close()
+		if got := s.Trash(); len(got) != 1 || got[0].ID != 2 {
+			t.Errorf("Trash() = %+v, want only task 2", got)
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package app
+
+import (
+	"time"
+)
+
+// Batch runs fn in a transaction of store when it is a Batcher, and
+// against store itself otherwise, where every change is saved on its own.
+func Batch(store TaskStore, fn func(tx TaskStore) error) error {
+	if b, ok := store.(Batcher); ok {
+		return b.Tx(fn)
+	}
+	return fn(store)
+}
+
+// Tx applies the changes fn makes through tx in memory and, if fn returns
+// nil, writes them to the journal in one go. If fn fails, or the journal
+// cannot be written, the tasks in memory are rolled back and the error is
+// returned. The store is locked until Tx returns, so fn must only use tx.
+func (s *Store) Tx(fn func(tx TaskStore) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.beginUnsafe(); err != nil {
+		return err
+	}
+	defer s.unlockUnsafe()
+
+	tx := &storeTx{
+		s:       s,
+		tasks:   append([]*Task(nil), s.tasks...),
+		trash:   append([]*Task(nil), s.trash...),
+		nextID:  s.NextID,
+		idFloor: s.idFloor,
+		orig:    map[int]*Task{},
+	}
+	err := fn(tx)
+	// The changes are replayed by commitUnsafe the way any other change
+	// is: written to the journal first, then applied.
+	tx.rollbackUnsafe()
+	if err != nil {
+		return err
+	}
+	return s.commitUnsafe(tx.entries...)
+}
+
+// storeTx is the view of a Store inside Store.Tx. Each change is applied
+// to the store's tasks as it is made and collected for the journal.
+type storeTx struct {
+	s       *Store
+	entries []journalEntry
+
+	// The state before the transaction, to roll back to.
+	tasks   []*Task
+	trash   []*Task
+	nextID  int
+	idFloor int
+	orig    map[int]*Task // indexed live task of each ID changed, nil if none
+}
+
+// stageUnsafe applies e and keeps it for the journal.
+func (tx *storeTx) stageUnsafe(e journalEntry) {
+	if _, ok := tx.orig[e.Task.ID]; !ok {
+		tx.orig[e.Task.ID] = tx.s.index.byID[e.Task.ID]
+	}
+	tx.s.applyUnsafe(e)
+	tx.entries = append(tx.entries, e)
+}
+
+// rollbackUnsafe puts the store's tasks back the way they were before the
+// transaction.
+func (tx *storeTx) rollbackUnsafe() {
+	s := tx.s
+	s.tasks, s.trash, s.NextID, s.idFloor = tx.tasks, tx.trash, tx.nextID, tx.idFloor
+	for id, t := range tx.orig {
+		s.index.remove(id)
+		if t != nil {
+			s.index.put(t)
+		}
+	}
+}
+
+func (tx *storeTx) List() []*Task {
+	return tx.s.listUnsafe()
+}
+
+func (tx *storeTx) ListByDate(date time.Time) []*Task {
+	return tx.s.index.between(date, date)
+}
+
+func (tx *storeTx) ListRange(from, to time.Time) []*Task {
+	return tx.s.index.between(from, to)
+}
+
+func (tx *storeTx) Overdue(now time.Time) []*Task {
+	return tx.s.index.overdue(now)
+}
+
+func (tx *storeTx) ListOpen() []*Task {
+	return tx.s.index.listOpen()
+}
+
+func (tx *storeTx) CompletedOn(day time.Time) []*Task {
+	return tx.s.index.completedOn(day)
+}
+
+func (tx *storeTx) ListProject(path string) []*Task {
+	return tx.s.index.inProject(path)
+}
+
+func (tx *storeTx) Get(id int) (*Task, error) {
+	return tx.s.getUnsafe(id)
+}
+
+func (tx *storeTx) Add(title, notes string, due *time.Time, date time.Time) (*Task, error) {
+	if title == "" {
+		return nil, ErrTitleRequired
+	}
+	e := tx.s.addEntryUnsafe(title, notes, due, date)
+	tx.stageUnsafe(e)
+	return cloneTask(e.Task), nil
+}
+
+func (tx *storeTx) Put(t *Task) (*Task, error) {
+	if t.Title == "" {
+		return nil, ErrTitleRequired
+	}
+	e := tx.s.putEntryUnsafe(t)
+	tx.stageUnsafe(e)
+	return cloneTask(e.Task), nil
+}
+
+func (tx *storeTx) Update(id int, opts UpdateOptions) (*Task, error) {
+	return tx.modify(id, opUpdate, updateTask(opts))
+}
+
+func (tx *storeTx) MarkCompleted(id int, completed bool) (*Task, error) {
+	return tx.modify(id, opComplete, markCompleted(completed))
+}
+
+func (tx *storeTx) ToggleCompleted(id int) (*Task, error) {
+	return tx.modify(id, opComplete, toggleCompleted)
+}
+
+func (tx *storeTx) Delete(id int) error {
+	_, err := tx.modify(id, opDelete, deleteTask)
+	return err
+}
+
+func (tx *storeTx) modify(id int, op string, fn func(t *Task, now time.Time) error) (*Task, error) {
+	cur := tx.s.findUnsafe(id)
+	if cur == nil {
+		return nil, ErrNotFound
+	}
+	e, err := changeEntry(cur, op, fn)
+	if err != nil {
+		return nil, err
+	}
+	tx.stageUnsafe(e)
+	return cloneTask(e.Task), nil
+}
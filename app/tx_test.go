@@ -0,0 +1,135 @@
+package app
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTx(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer s.Close()
+	yesterday := time.Now().AddDate(0, 0, -1)
+	today := time.Now()
+	a, _ := s.Add("write report", "", nil, yesterday)
+	b, _ := s.Add("call bob", "", nil, yesterday)
+
+	// Move the overdue tasks to today and add one more, in one go.
+	err = s.Tx(func(tx TaskStore) error {
+		for _, t := range tx.Overdue(today) {
+			if _, err := tx.Update(t.ID, UpdateOptions{Date: &today}); err != nil {
+				return err
+			}
+		}
+		if got := len(tx.Overdue(today)); got != 0 {
+			t.Errorf("len(Overdue()) inside Tx = %d, want 0", got)
+		}
+		_, err := tx.Add("plan week", "", nil, today)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Tx() error = %v", err)
+	}
+	if got := len(s.ListByDate(today)); got != 3 {
+		t.Errorf("len(ListByDate(today)) = %d, want 3", got)
+	}
+	data, _ := os.ReadFile(s.journalPath())
+	if got := strings.Count(string(data), "\n"); got != 5 {
+		t.Errorf("journal has %d entries, want 5", got)
+	}
+
+	// A failing step drops the earlier ones.
+	fail := errors.New("stop")
+	err = s.Tx(func(tx TaskStore) error {
+		tx.Delete(a.ID)
+		tx.MarkCompleted(b.ID, true)
+		tx.Add("never", "", nil, today)
+		if _, err := tx.Update(b.ID, UpdateOptions{Title: new(string)}); !errors.Is(err, ErrTitleRequired) {
+			t.Errorf("Update() with empty title error = %v, want ErrTitleRequired", err)
+		}
+		return fail
+	})
+	if !errors.Is(err, fail) {
+		t.Fatalf("Tx() error = %v, want %v", err, fail)
+	}
+	if got := len(s.ListByDate(today)); got != 3 {
+		t.Errorf("len(ListByDate(today)) after rollback = %d, want 3", got)
+	}
+	if got, _ := s.Get(b.ID); got.IsCompleted() {
+		t.Errorf("task %d completed after rollback", b.ID)
+	}
+	if got := len(s.Trash()); got != 0 {
+		t.Errorf("len(Trash()) after rollback = %d, want 0", got)
+	}
+	if task, _ := s.Add("next", "", nil, today); task.ID != 4 {
+		t.Errorf("Add() after rollback got ID %d, want 4", task.ID)
+	}
+
+	s.Close()
+	s, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := len(s.ListByDate(today)); got != 4 {
+		t.Errorf("len(ListByDate(today)) after reopening = %d, want 4", got)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	for _, backend := range []string{BackendJSON, BackendSQLite, BackendMarkdown} {
+		t.Run(backend, func(t *testing.T) {
+			store, err := Open(backend, filepath.Join(t.TempDir(), "tasks"+fileExt(backend)))
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			t.Cleanup(func() { store.(io.Closer).Close() })
+
+			day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+			err = Batch(store, func(tx TaskStore) error {
+				for _, title := range []string{"one", "two"} {
+					if _, err := tx.Add(title, "", nil, day); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Batch() error = %v", err)
+			}
+			if got := len(store.ListByDate(day)); got != 2 {
+				t.Errorf("len(ListByDate()) = %d, want 2", got)
+			}
+
+			fail := errors.New("stop")
+			err = Batch(store, func(tx TaskStore) error {
+				if _, err := tx.Add("dropped", "", nil, day); err != nil {
+					return err
+				}
+				if _, err := tx.MarkCompleted(1, true); err != nil {
+					return err
+				}
+				if got := len(tx.ListByDate(day)); got != 3 {
+					t.Errorf("len(ListByDate()) inside Batch = %d, want 3", got)
+				}
+				return fail
+			})
+			if !errors.Is(err, fail) {
+				t.Fatalf("Batch() error = %v, want %v", err, fail)
+			}
+			if got := len(store.ListByDate(day)); got != 2 {
+				t.Errorf("len(ListByDate()) after a failed Batch = %d, want 2", got)
+			}
+			if task, err := store.Get(1); err != nil || task.IsCompleted() {
+				t.Errorf("Get(1) after a failed Batch = %+v, %v, want it open", task, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Every task carries a UUID that identifies it across stores, exports and
+// synced copies, where the numeric IDs of different files collide. New
+// tasks get a random one. Tasks saved before UUIDs existed get one derived
+// from their ID and creation time, so that every copy of such a task, in
+// the snapshot, the journal, a backup or another device's file, agrees on
+// it without the file being rewritten first.
+
+// legacyNamespace scopes the UUIDs derived for tasks saved without one.
+var legacyNamespace = uuid.MustParse("5f0c3a8e-2d1b-4c6a-9e57-7b4f1d2a9c30")
+
+func newUUID() string {
+	return uuid.NewString()
+}
+
+// legacyUUID is the UUID of a task with the given ID and creation time
+// that was saved without one.
+func legacyUUID(id int, createdAt time.Time) string {
+	key := fmt.Sprintf("%d/%s", id, createdAt.UTC().Format(time.RFC3339Nano))
+	return uuid.NewSHA1(legacyNamespace, []byte(key)).String()
+}
+
+// ensureUUID gives t the UUID of cur, the task it replaces, or a new one
+// if it has none yet.
+func ensureUUID(t, cur *Task) {
+	switch {
+	case t.UUID != "":
+	case cur != nil:
+		t.UUID = cur.UUID
+	default:
+		t.UUID = newUUID()
+	}
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUUIDs(t *testing.T) {
+	for _, backend := range []string{BackendJSON, BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tasks"+fileExt(backend))
+			store, err := Open(backend, path)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+			a, _ := store.Add("write report", "", nil, day)
+			b, _ := store.Add("call bob", "", nil, day)
+			if a.UUID == "" || a.UUID == b.UUID {
+				t.Fatalf("UUIDs = %q, %q, want two different ones", a.UUID, b.UUID)
+			}
+			title := "write the report"
+			if got, _ := store.Update(a.ID, UpdateOptions{Title: &title}); got.UUID != a.UUID {
+				t.Errorf("Update() changed the UUID to %q", got.UUID)
+			}
+			// A copy without a UUID takes the one of the task it replaces.
+			cp := *b
+			cp.UUID = ""
+			if got, _ := store.Put(&cp); got.UUID != b.UUID {
+				t.Errorf("Put() UUID = %q, want %q", got.UUID, b.UUID)
+			}
+
+			store.(io.Closer).Close()
+			store, err = Open(backend, path)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer store.(io.Closer).Close()
+			if got, _ := store.Get(a.ID); got.UUID != a.UUID {
+				t.Errorf("UUID after reopening = %q, want %q", got.UUID, a.UUID)
+			}
+		})
+	}
+}
+
+func TestUUIDBackfill(t *testing.T) {
+	// Tasks saved before UUIDs get the same one on every load.
+	data, err := os.ReadFile(filepath.Join("testdata", "schema", "v2.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	os.WriteFile(path, data, 0o644)
+	var uuids []string
+	for i := 0; i < 2; i++ {
+		s, err := Load(path)
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		task, _ := s.Get(1)
+		uuids = append(uuids, task.UUID)
+		s.Close()
+	}
+	created := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	if uuids[0] != legacyUUID(1, created) || uuids[1] != uuids[0] {
+		t.Errorf("UUIDs = %q, want %q twice", uuids, legacyUUID(1, created))
+	}
+
+	// SQLite rows saved before UUIDs get the same one as in a JSON store.
+	path = filepath.Join(t.TempDir(), "tasks.db")
+	s, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	task, _ := s.Add("write report", "", nil, created)
+	s.db.Exec(`UPDATE tasks SET uuid = NULL`)
+	s.Close()
+	if s, err = OpenSQLite(path); err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer s.Close()
+	var stored string
+	s.db.QueryRow(`SELECT uuid FROM tasks WHERE id = ?`, task.ID).Scan(&stored)
+	if want := legacyUUID(task.ID, task.CreatedAt); stored != want {
+		t.Errorf("backfilled UUID = %q, want %q", stored, want)
+	}
+}
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce coalesces the bursts of events a single save produces
+// (temp file, rename, journal append) into one reload.
+const watchDebounce = 100 * time.Millisecond
+
+// Watcher is implemented by stores that can notice changes made to their
+// backing files by other processes.
+type Watcher interface {
+	// Watch reloads the store whenever its files change underneath it and
+	// then calls onChange from a background goroutine.
+	Watch(onChange func()) error
+}
+
+var (
+	_ Watcher = (*Store)(nil)
+	_ Watcher = (*SQLiteStore)(nil)
+	_ Watcher = (*MarkdownStore)(nil)
+)
+
+// Watch reloads the store whenever its files are changed by another process
+// and then calls onChange. Changes made through this Store do not trigger it.
+func (s *Store) Watch(onChange func()) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.watcher != nil {
+		return fmt.Errorf("store is already watched")
+	}
+	names := []string{filepath.Base(s.path), filepath.Base(s.journalPath())}
+	w, err := watchFiles(filepath.Dir(s.path), namedFiles(names...), func() {
+		if changed, err := s.refresh(); err == nil && changed {
+			onChange()
+		}
+	})
+	if err != nil {
+		return err
+	}
+	s.watcher = w
+	return nil
+}
+
+// refresh catches up with changes on disk and reports whether there were any.
+func (s *Store) refresh() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.lockUnsafe(); err != nil {
+		return false, err
+	}
+	defer s.unlockUnsafe()
+	return s.syncUnsafe()
+}
+
+// Watch calls onChange whenever another connection commits to the database.
+func (s *SQLiteStore) Watch(onChange func()) error {
+	if s.watcher != nil {
+		return fmt.Errorf("store is already watched")
+	}
+	version, err := s.dataVersion()
+	if err != nil {
+		return err
+	}
+	base := filepath.Base(s.path)
+	w, err := watchFiles(filepath.Dir(s.path), namedFiles(base, base+"-wal"), func() {
+		// data_version only moves when a different connection commits.
+		if v, err := s.dataVersion(); err == nil && v != version {
+			version = v
+			onChange()
+		}
+	})
+	if err != nil {
+		return err
+	}
+	s.watcher = w
+	return nil
+}
+
+func (s *SQLiteStore) dataVersion() (int64, error) {
+	var v int64
+	if err := s.db.QueryRow("PRAGMA data_version").Scan(&v); err != nil {
+		return 0, fmt.Errorf("read data version: %w", err)
+	}
+	return v, nil
+}
+
+// watchFiles calls fn, debounced, whenever a file in dir whose name matches
+// is written, created, renamed or removed. The directory is watched rather
+// than the files because atomic saves replace them.
+func watchFiles(dir string, match func(name string) bool, fn func()) (*fsnotify.Watcher, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("mkdir store dir: %w", err)
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("watch store: %w", err)
+	}
+	if err := w.Add(dir); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("watch store: %w", err)
+	}
+
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case ev, ok := <-w.Events:
+				if !ok {
+					if timer != nil {
+						timer.Stop()
+					}
+					return
+				}
+				if ev.Has(fsnotify.Chmod) || !match(filepath.Base(ev.Name)) {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(watchDebounce, fn)
+			case _, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+	return w, nil
+}
+
+// namedFiles returns a match for watchFiles of the given file names.
+func namedFiles(names ...string) func(string) bool {
+	return func(name string) bool {
+		return slices.Contains(names, name)
+	}
+}
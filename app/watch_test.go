@@ -0,0 +1,43 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	a, _ := Load(path)
+	defer a.Close()
+	changed := make(chan struct{}, 10)
+	if err := a.Watch(func() { changed <- struct{}{} }); err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	// Our own writes are already in memory and must not trigger a reload.
+	if _, err := a.Add("own", "", nil, day); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	select {
+	case <-changed:
+		t.Fatalf("Watch() fired for a change made through the same store")
+	case <-time.After(3 * watchDebounce):
+	}
+
+	b, _ := Load(path)
+	defer b.Close()
+	if _, err := b.Add("other", "", nil, day); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Watch() did not fire for a change made by another store")
+	}
+	if got := len(a.List()); got != 2 {
+		t.Errorf("len(List()) after reload = %d, want 2", got)
+	}
+}
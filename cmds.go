@@ -1,12 +1,18 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"os"
+	"taskman/app"
 	"taskman/components/calendar"
 	"taskman/components/config"
 	"taskman/components/footer"
+	"taskman/components/projects"
 	"taskman/components/results"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	zone "github.com/lrstanley/bubblezone"
@@ -28,30 +34,42 @@ var rootCmd = &cobra.Command{
 
 Taskman is a CLI tool for Task API.`,
 	Version: version,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return validateList(activeList())
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		config.SetVersion(version)
-		viper.SetConfigName("config")         // name of config file (without extension)
-		viper.SetConfigType("json")           // REQUIRED if the config file does not have the extension in the name
-		viper.AddConfigPath("/etc/taskman/")  // path to look for the config file in
-		viper.AddConfigPath("$HOME/.taskman") // call multiple times to add many search paths
-		err := viper.ReadInConfig()           // Find and read the config file
-		if err != nil {                       // Handle errors reading the config file
-			if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-				// NOTE: ignore if config file is not found
-			} else {
-				panic(fmt.Errorf("fatal error config file: %w", err))
-			}
+		config.SetTagColors(viper.GetStringMapString("tags.colors"))
+
+		var (
+			store  app.TaskStore
+			err    error
+			legacy = legacyStore() // before opening creates the store
+		)
+		if needsPassphrase() {
+			store, err = unlockStore()
+		} else {
+			store, err = openStore()
+		}
+		if err != nil {
+			fmt.Println("could not open task store:", err)
+			os.Exit(1)
+		}
+		if store == nil {
+			return // gave up on the passphrase
 		}
 
 		// ----
 		zone.NewGlobal()
 
 		footerBox := footer.New()
-		resultsBox := results.New()
-		calendarBox := calendar.New()
+		resultsBox := results.New(store)
+		calendarBox := calendar.New(activeList())
+		projectsBox := projects.New(store)
 
 		// layout-tree defintion
-		m := Model{tui: boxer.Boxer{}}
+		m := Model{tui: boxer.Boxer{}, store: store, list: activeList(), legacy: legacy, events: app.NewEventQueue()}
+		m.subscribe(store)
 
 		rootNode := boxer.CreateNoBorderNode()
 		rootNode.VerticalStacked = true
@@ -65,14 +83,21 @@ Taskman is a CLI tool for Task API.`,
 		centerNode := boxer.CreateNoBorderNode()
 		centerNode.VerticalStacked = false
 		centerNode.SizeFunc = func(node boxer.Node, widthOrHeight int) []int {
+			if len(node.Children) == 2 {
+				// without the projects pane, see Model.layout
+				return []int{widthOrHeight - 30, 30}
+			}
 			return []int{
-				widthOrHeight - 30,
+				widthOrHeight - 28 - 30,
+				28,
 				30,
 			}
 		}
 
+		m.projectsLeaf = stripErr(m.tui.CreateLeaf("projects", projectsBox))
 		centerNode.Children = []boxer.Node{
 			stripErr(m.tui.CreateLeaf("results", resultsBox)),
+			m.projectsLeaf,
 			stripErr(m.tui.CreateLeaf("calendar", calendarBox)),
 		}
 
@@ -90,15 +115,252 @@ Taskman is a CLI tool for Task API.`,
 			defer f.Close()
 		}
 
-		p := tea.NewProgram(
+		// Pick up edits made by other processes while the TUI is open.
+		var p *tea.Program
+		m.watch = func(store app.TaskStore) {
+			if w, ok := store.(app.Watcher); ok {
+				if err := w.Watch(func() { p.Send(app.StoreChangedMsg{}) }); err != nil {
+					log.Println("could not watch task store:", err)
+				}
+			}
+		}
+		m.watch(store)
+
+		p = tea.NewProgram(
 			m,
 			tea.WithAltScreen(),       // use the full size of the terminal in its "alternate screen buffer"
 			tea.WithMouseCellMotion(), // turn on mouse support so we can track the mouse wheel
 		)
 
-		if _, err := p.Run(); err != nil {
+		final, err := p.Run()
+		if fm, ok := final.(Model); ok {
+			store = fm.store // the list open when the TUI quit
+		}
+		closeStore(store)
+		if err != nil {
 			fmt.Println("could not run program:", err)
 			os.Exit(1)
 		}
 	},
 }
+
+var migrateStoreCmd = &cobra.Command{
+	Use:   "migrate-store",
+	Short: "Copy all tasks into another storage backend",
+	Long: `Copy all tasks from the configured store (store.backend) into another
+storage backend, keeping IDs and timestamps. The tasks of the active list
+go to a file next to it with the new backend's extension, which must be
+empty.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		to, _ := cmd.Flags().GetString("to")
+		from := viper.GetString("store.backend")
+		if to == from {
+			return fmt.Errorf("store already uses the %s backend", to)
+		}
+
+		src, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer closeStore(src)
+		path := app.MigratePath(storePath(), from, to)
+		dst, err := app.Open(to, path, storeOptions()...)
+		if err != nil {
+			return err
+		}
+		defer closeStore(dst)
+		n, err := app.Migrate(src, dst)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Copied %d tasks to %s.\n", n, path)
+		fmt.Printf("Set \"store.backend\" to %q in your config to use it.\n", to)
+		if viper.GetString("store.path") != "" {
+			fmt.Printf("Point \"store.path\" at %s as well.\n", app.MigratePath(baseStorePath(), from, to))
+		}
+		return nil
+	},
+}
+
+func init() {
+	cobra.OnInitialize(initConfig)
+
+	rootCmd.PersistentFlags().String("store", "", "task store file (default $XDG_DATA_HOME/taskman/tasks.json)")
+	viper.BindPFlag("store.path", rootCmd.PersistentFlags().Lookup("store"))
+	rootCmd.PersistentFlags().String("list", "", "task list to open (default list.default)")
+	viper.BindPFlag("list.default", rootCmd.PersistentFlags().Lookup("list"))
+
+	migrateStoreCmd.Flags().String("to", app.BackendSQLite, "destination backend (json, sqlite or markdown)")
+	rootCmd.AddCommand(migrateStoreCmd)
+}
+
+func initConfig() {
+	viper.SetConfigName("config")         // name of config file (without extension)
+	viper.SetConfigType("json")           // REQUIRED if the config file does not have the extension in the name
+	viper.AddConfigPath("/etc/taskman/")  // path to look for the config file in
+	viper.AddConfigPath("$HOME/.taskman") // call multiple times to add many search paths
+	viper.SetDefault("store.backend", app.BackendJSON)
+	viper.SetDefault("store.compact_every", app.DefaultCompactEvery)
+	viper.SetDefault("backup.enabled", true)
+	viper.SetDefault("backup.every", app.DefaultBackupEvery)
+	viper.SetDefault("backup.keep", app.DefaultBackupKeep)
+	viper.SetDefault("trash.retention_days", int(app.DefaultTrashRetention.Hours()/24))
+	viper.SetDefault("archive.after_days", int(app.DefaultArchiveAfter.Hours()/24))
+	viper.SetDefault("git.enabled", false)
+	viper.SetDefault("git.remote", app.DefaultGitRemote)
+	err := viper.ReadInConfig() // Find and read the config file
+	if err != nil {             // Handle errors reading the config file
+		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
+			// NOTE: ignore if config file is not found
+		} else {
+			panic(fmt.Errorf("fatal error config file: %w", err))
+		}
+	}
+}
+
+// openStore opens the active task list with the backend selected by the
+// "store.backend" config key, unlocking it if it is encrypted.
+func openStore() (app.TaskStore, error) {
+	if _, err := resolveSecret(); err != nil {
+		return nil, err
+	}
+	return openList(activeList())
+}
+
+// openList opens the named task list, creating it if needed. An encrypted
+// list must use the secret the active one was unlocked with. A list in
+// plain JSON is only given that secret, and so encrypted on its next save,
+// when it is a key file from "store.key_file".
+func openList(name string) (app.TaskStore, error) {
+	if err := validateList(name); err != nil {
+		return nil, err
+	}
+	path := app.ListPath(baseStorePath(), name)
+	opts := storeOptions()
+	if storeSecret != nil {
+		encrypted, err := app.IsEncrypted(path)
+		if err != nil {
+			return nil, err
+		}
+		if encrypted || viper.GetString("store.key_file") != "" {
+			opts = append(opts, app.WithSecret(storeSecret))
+		}
+	}
+	_, statErr := os.Stat(path)
+	store, err := app.Open(viper.GetString("store.backend"), path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// Write a new list out right away, so it shows up among the lists.
+	if s, ok := store.(*app.Store); ok && errors.Is(statErr, os.ErrNotExist) {
+		if err := s.Save(); err != nil {
+			closeStore(store)
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// storePath returns the file of the active task list.
+func storePath() string {
+	return app.ListPath(baseStorePath(), activeList())
+}
+
+// baseStorePath returns the file of the configured task store: the --store
+// flag, else "store.path", else the backend's file in $XDG_DATA_HOME. The
+// other task lists are kept next to it.
+func baseStorePath() string {
+	if path := viper.GetString("store.path"); path != "" {
+		return path
+	}
+	return app.DefaultPath(viper.GetString("store.backend"))
+}
+
+// validateList checks a list name before it is turned into a file name.
+// The list of the store file itself is fine, whatever that file is called.
+func validateList(name string) error {
+	if name == app.ListName(baseStorePath()) {
+		return nil
+	}
+	return app.ValidateListName(name)
+}
+
+// activeList returns the name of the task list to open: the --list flag,
+// else "list.default", else the list of the store file itself.
+func activeList() string {
+	if name := viper.GetString("list.default"); name != "" {
+		return name
+	}
+	return app.ListName(baseStorePath())
+}
+
+// legacyStore returns the task file an older version kept in the working
+// directory, if there is one and the configured store does not exist yet.
+func legacyStore() string {
+	legacy := app.LegacyPath(viper.GetString("store.backend"))
+	if _, err := os.Stat(legacy); err != nil {
+		return ""
+	}
+	path := storePath()
+	for _, p := range []string{path, path + ".journal"} {
+		if _, err := os.Stat(p); err == nil {
+			return ""
+		}
+	}
+	return legacy
+}
+
+// importLegacy copies the tasks of a legacy task file into store. The
+// legacy file is left as it is.
+func importLegacy(store app.TaskStore, legacy string) (int, error) {
+	var opts []app.Option
+	if storeSecret != nil {
+		opts = append(opts, app.WithSecret(storeSecret))
+	}
+	src, err := app.Open(viper.GetString("store.backend"), legacy, opts...)
+	if err != nil {
+		return 0, err
+	}
+	defer closeStore(src)
+	return app.Migrate(src, store)
+}
+
+// storeOptions maps the "store.*", "backup.*", "trash.*", "archive.*" and
+// "git.*" config keys onto store options.
+func storeOptions() []app.Option {
+	opts := []app.Option{
+		app.WithCompactEvery(viper.GetInt("store.compact_every")),
+		app.WithTrashRetention(time.Duration(viper.GetInt("trash.retention_days")) * 24 * time.Hour),
+		app.WithArchiveAfter(time.Duration(viper.GetInt("archive.after_days")) * 24 * time.Hour),
+	}
+	if viper.GetBool("git.enabled") {
+		opts = append(opts, app.WithGit())
+	}
+	if viper.GetBool("backup.enabled") {
+		opts = append(opts, app.WithBackups(
+			viper.GetString("backup.dir"),
+			viper.GetInt("backup.every"),
+			viper.GetInt("backup.keep"),
+		))
+	}
+	return opts
+}
+
+// storeBackups returns the backup set of the configured task store. Call it
+// after openStore, so backups of an encrypted store can be opened.
+func storeBackups() app.Backups {
+	b := app.NewBackups(storePath(), viper.GetString("backup.dir"), viper.GetInt("backup.keep"))
+	b.Secret = storeSecret
+	return b
+}
+
+// closeStore flushes and releases stores that hold open resources.
+func closeStore(store app.TaskStore) {
+	if c, ok := store.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			fmt.Println("could not close task store:", err)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"taskman/app"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "Move old completed tasks into the yearly archive files",
+	Long: `Completed tasks are moved into archive-YYYY.json files, which are only
+read when asked for, once they are "archive.after_days" days old. This
+command archives the tasks scheduled and completed before --before, which
+defaults to that age. Only the json backend keeps an archive.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		before := time.Now().AddDate(0, 0, -viper.GetInt("archive.after_days"))
+		if s, _ := cmd.Flags().GetString("before"); s != "" {
+			day, err := time.ParseInLocation("2006-01-02", s, time.Local)
+			if err != nil {
+				return fmt.Errorf("invalid date %q: use YYYY-MM-DD", s)
+			}
+			before = day
+		}
+		archive, closeFn, err := openArchive()
+		if err != nil {
+			return err
+		}
+		defer closeFn()
+
+		n, err := archive.Archive(before)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Archived %d tasks.\n", n)
+		return nil
+	},
+}
+
+var archiveListCmd = &cobra.Command{
+	Use:          "list [year]",
+	Short:        "List archived tasks, or the archived years without a year",
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		archive, closeFn, err := openArchive()
+		if err != nil {
+			return err
+		}
+		defer closeFn()
+
+		if len(args) == 0 {
+			years, err := archive.ArchiveYears()
+			if err != nil {
+				return err
+			}
+			if len(years) == 0 {
+				fmt.Println("Archive is empty.")
+			}
+			for _, year := range years {
+				fmt.Println(year)
+			}
+			return nil
+		}
+
+		year, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid year %q", args[0])
+		}
+		tasks, err := archive.Archived(year)
+		if err != nil {
+			return err
+		}
+		if len(tasks) == 0 {
+			fmt.Printf("No tasks archived for %d.\n", year)
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tDAY\tCOMPLETED\tTITLE")
+		for _, t := range tasks {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", t.ID, t.Date.Format("2006-01-02"), t.CompletedAt.Format("2006-01-02 15:04"), t.Title)
+		}
+		return w.Flush()
+	},
+}
+
+var archiveRestoreCmd = &cobra.Command{
+	Use:          "restore <id>...",
+	Short:        "Move archived tasks back into the task list",
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ids, err := parseIDs(args)
+		if err != nil {
+			return err
+		}
+		archive, closeFn, err := openArchive()
+		if err != nil {
+			return err
+		}
+		defer closeFn()
+
+		for _, id := range ids {
+			t, err := archive.Unarchive(id)
+			if err != nil {
+				return fmt.Errorf("restore task %d: %w", id, err)
+			}
+			fmt.Printf("Restored #%d %s.\n", t.ID, t.Title)
+		}
+		return nil
+	},
+}
+
+// openArchive opens the configured store and returns its archive, along
+// with a function that closes the store.
+func openArchive() (app.Archiver, func(), error) {
+	store, err := openStore()
+	if err != nil {
+		return nil, nil, err
+	}
+	archive, ok := store.(app.Archiver)
+	if !ok {
+		closeStore(store)
+		return nil, nil, fmt.Errorf("store does not keep an archive")
+	}
+	return archive, func() { closeStore(store) }, nil
+}
+
+func init() {
+	archiveCmd.Flags().String("before", "", "archive tasks completed before this day (YYYY-MM-DD)")
+	archiveCmd.AddCommand(archiveListCmd, archiveRestoreCmd)
+	rootCmd.AddCommand(archiveCmd)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"taskman/app"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "List, create and restore task store backups",
+	Long: `Taskman copies the task store into a backups directory before the first
+change of every session and then every "backup.every" changes, keeping the
+newest "backup.keep" copies. These commands manage the copies by hand.`,
+}
+
+var backupListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List backups and how they differ from the current store",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer closeStore(store)
+
+		backups, err := storeBackups().List()
+		if err != nil {
+			return err
+		}
+		if len(backups) == 0 {
+			fmt.Println("No backups yet.")
+			return nil
+		}
+
+		current := len(store.List())
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tCREATED\tTASKS\tDIFF")
+		for _, b := range backups {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%+d\n", b.ID, b.Time.Format("2006-01-02 15:04:05"), b.Tasks, b.Tasks-current)
+		}
+		fmt.Fprintf(w, "current\t\t%d\t\n", current)
+		return w.Flush()
+	},
+}
+
+var backupCreateCmd = &cobra.Command{
+	Use:          "create",
+	Short:        "Back up the current store now",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer closeStore(store)
+
+		b, err := storeBackups().Create(store.List())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created backup %s with %d tasks.\n", b.ID, b.Tasks)
+		return nil
+	},
+}
+
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore <id>",
+	Short: "Replace the store with a backup",
+	Long: `Replace every task in the store with the contents of a backup.
+The current state is backed up first, so a restore can itself be undone.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer closeStore(store)
+
+		replacer, ok := store.(app.Replacer)
+		if !ok {
+			return fmt.Errorf("store does not support restoring backups")
+		}
+
+		backups := storeBackups()
+		tasks, err := backups.Read(args[0])
+		if err != nil {
+			return err
+		}
+		safety, err := backups.Create(store.List())
+		if err != nil {
+			return err
+		}
+		if err := replacer.ReplaceAll(tasks); err != nil {
+			return err
+		}
+		fmt.Printf("Restored %d tasks from %s (previous state saved as %s).\n", len(tasks), args[0], safety.ID)
+		return nil
+	},
+}
+
+func init() {
+	backupCmd.AddCommand(backupListCmd, backupCreateCmd, backupRestoreCmd)
+	rootCmd.AddCommand(backupCmd)
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"taskman/app"
+	"taskman/components/passphrase"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// passphraseEnv may hold the passphrase of an encrypted store, so scripts
+// can use it without a prompt.
+const passphraseEnv = "TASKMAN_PASSPHRASE"
+
+var encryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt the task store and its backups",
+	Long: `Rewrite the JSON task store and its backups encrypted with a passphrase or,
+with --key-file, with a key file. A key file that does not exist yet is
+created with a new random key.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if backend := viper.GetString("store.backend"); backend != app.BackendJSON {
+			return fmt.Errorf("encryption is only supported by the %s backend", app.BackendJSON)
+		}
+		path := storePath()
+		if encrypted, err := app.IsEncrypted(path); err != nil {
+			return err
+		} else if encrypted {
+			return fmt.Errorf("%s is already encrypted", path)
+		}
+
+		keyFile, _ := cmd.Flags().GetString("key-file")
+		if keyFile == "" {
+			keyFile = viper.GetString("store.key_file")
+		}
+		var (
+			secret *app.Secret
+			err    error
+		)
+		switch _, statErr := os.Stat(keyFile); {
+		case keyFile == "":
+			secret, err = newPassphrase()
+		case errors.Is(statErr, os.ErrNotExist):
+			if secret, err = app.CreateKeyFile(keyFile); err == nil {
+				fmt.Printf("Created key file %s. Keep it safe: without it the tasks are lost.\n", keyFile)
+			}
+		default:
+			secret, err = app.ReadKeyFile(keyFile)
+		}
+		if err != nil {
+			return err
+		}
+
+		store, err := app.Load(path, storeOptions()...)
+		if err != nil {
+			return err
+		}
+		defer closeStore(store)
+		if err := store.Rekey(secret); err != nil {
+			return err
+		}
+		backups := app.NewBackups(path, viper.GetString("backup.dir"), viper.GetInt("backup.keep"))
+		if err := backups.Rekey(secret); err != nil {
+			return err
+		}
+
+		fmt.Printf("Encrypted %s and its backups.\n", path)
+		if keyFile != "" && keyFile != viper.GetString("store.key_file") {
+			if abs, err := filepath.Abs(keyFile); err == nil {
+				keyFile = abs
+			}
+			fmt.Printf("Set \"store.key_file\" to %q in your config to open it.\n", keyFile)
+		}
+		return nil
+	},
+}
+
+var decryptCmd = &cobra.Command{
+	Use:          "decrypt",
+	Short:        "Store the tasks and their backups in plain JSON again",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opened, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer closeStore(opened)
+		store, ok := opened.(*app.Store)
+		if !ok || storeSecret == nil {
+			return fmt.Errorf("%s is not encrypted", storePath())
+		}
+
+		if err := store.Rekey(nil); err != nil {
+			return err
+		}
+		if err := storeBackups().Rekey(nil); err != nil {
+			return err
+		}
+		fmt.Printf("Decrypted %s and its backups.\n", storePath())
+		if viper.GetString("store.key_file") != "" {
+			fmt.Println("Remove \"store.key_file\" from your config, or the store is encrypted again on its next save.")
+		}
+		return nil
+	},
+}
+
+// storeSecret is what the task store was unlocked with, once resolveSecret
+// has run; nil for a store in plain JSON.
+var (
+	storeSecret    *app.Secret
+	secretResolved bool
+)
+
+// resolveSecret returns the secret of the task store: the key file from
+// "store.key_file", or for an encrypted store the passphrase from
+// TASKMAN_PASSPHRASE or typed at the terminal.
+func resolveSecret() (*app.Secret, error) {
+	if secretResolved {
+		return storeSecret, nil
+	}
+	var (
+		secret *app.Secret
+		err    error
+	)
+	if keyFile := viper.GetString("store.key_file"); keyFile != "" {
+		secret, err = app.ReadKeyFile(keyFile)
+	} else if viper.GetString("store.backend") == app.BackendJSON {
+		var encrypted bool
+		if encrypted, err = app.IsEncrypted(storePath()); err == nil && encrypted {
+			var p []byte
+			if p, err = readPassphrase("Passphrase: "); err == nil {
+				secret = app.NewSecret(p)
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	storeSecret, secretResolved = secret, true
+	return secret, nil
+}
+
+// needsPassphrase reports whether opening the store needs a passphrase
+// that neither the config nor the environment provides.
+func needsPassphrase() bool {
+	if viper.GetString("store.key_file") != "" || os.Getenv(passphraseEnv) != "" {
+		return false
+	}
+	if viper.GetString("store.backend") != app.BackendJSON {
+		return false
+	}
+	encrypted, err := app.IsEncrypted(storePath())
+	return err == nil && encrypted
+}
+
+// unlockStore asks for the passphrase in a popup until the store opens.
+// It returns nil if the user gives up.
+func unlockStore() (app.TaskStore, error) {
+	var store app.TaskStore
+	prompt := passphrase.New(func(p []byte) error {
+		secret := app.NewSecret(p)
+		opts := append(storeOptions(), app.WithSecret(secret))
+		s, err := app.Open(viper.GetString("store.backend"), storePath(), opts...)
+		if err != nil {
+			return err
+		}
+		store, storeSecret, secretResolved = s, secret, true
+		return nil
+	})
+	if _, err := tea.NewProgram(prompt, tea.WithAltScreen()).Run(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// readPassphrase returns the passphrase from TASKMAN_PASSPHRASE or reads it
+// from the terminal without echo.
+func readPassphrase(prompt string) ([]byte, error) {
+	if p := os.Getenv(passphraseEnv); p != "" {
+		return []byte(p), nil
+	}
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		return nil, fmt.Errorf("%w (set %s)", app.ErrEncrypted, passphraseEnv)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	p, err := term.ReadPassword(os.Stdin.Fd())
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("read passphrase: %w", err)
+	}
+	return p, nil
+}
+
+// newPassphrase asks for a new passphrase twice, unless it comes from the
+// environment.
+func newPassphrase() (*app.Secret, error) {
+	p, err := readPassphrase("New passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if len(p) == 0 {
+		return nil, fmt.Errorf("passphrase must not be empty")
+	}
+	if os.Getenv(passphraseEnv) == "" {
+		again, err := readPassphrase("Repeat passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(p, again) {
+			return nil, fmt.Errorf("passphrases do not match")
+		}
+	}
+	return app.NewSecret(p), nil
+}
+
+func init() {
+	encryptCmd.Flags().String("key-file", "", "encrypt with this key file instead of a passphrase (default store.key_file)")
+	rootCmd.AddCommand(encryptCmd, decryptCmd)
+}
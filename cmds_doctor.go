@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"taskman/app"
+
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor [rule]...",
+	Short: "Check the task store for broken tasks and repair them",
+	Long: `Check every task in the store against the rules below, or only the ones
+given, and print the tasks that break them. Hand edits and sync conflicts
+can leave such tasks behind. Tasks in the trash or the archive are left
+as they are, but a task sharing an ID or UUID with one of them is found.
+
+With --fix the tasks are repaired as the rules describe. The store is
+backed up first, so a repair can be undone with "taskman backup restore".
+
+Rules:
+` + doctorRulesHelp(),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rules := app.DoctorRules
+		if len(args) > 0 {
+			rules = nil
+			for _, name := range args {
+				r, ok := app.FindDoctorRule(name)
+				if !ok {
+					return fmt.Errorf("unknown rule %q", name)
+				}
+				rules = append(rules, r)
+			}
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer closeStore(store)
+
+		tasks, parked, err := app.KnownTasks(store)
+		if err != nil {
+			return err
+		}
+		if fix, _ := cmd.Flags().GetBool("fix"); !fix {
+			findings := app.Diagnose(tasks, parked, rules)
+			for _, f := range findings {
+				fmt.Println(f)
+			}
+			if len(findings) > 0 {
+				return fmt.Errorf("found %d problems; run taskman doctor --fix to repair them", len(findings))
+			}
+			fmt.Printf("Checked %d tasks, no problems found.\n", len(tasks))
+			return nil
+		}
+
+		replacer, ok := store.(app.Replacer)
+		if !ok {
+			return fmt.Errorf("store does not support repairs")
+		}
+		nextID, err := app.FreeID(store)
+		if err != nil {
+			return err
+		}
+		repaired, findings := app.Repair(tasks, parked, nextID, rules)
+		if len(findings) == 0 {
+			fmt.Printf("Checked %d tasks, no problems found.\n", len(tasks))
+			return nil
+		}
+		safety, err := storeBackups().Create(tasks)
+		if err != nil {
+			return err
+		}
+		if err := replacer.ReplaceAll(repaired); err != nil {
+			return err
+		}
+		for _, f := range findings {
+			fmt.Println("fixed", f)
+		}
+		fmt.Printf("Fixed %d problems (previous state saved as %s).\n", len(findings), safety.ID)
+		return nil
+	},
+}
+
+func doctorRulesHelp() string {
+	var b strings.Builder
+	for _, r := range app.DoctorRules {
+		fmt.Fprintf(&b, "  %-26s %s\n", r.Name, r.Description)
+	}
+	return b.String()
+}
+
+func init() {
+	doctorCmd.Flags().Bool("fix", false, "repair the problems found, after backing up the store")
+	rootCmd.AddCommand(doctorCmd)
+}
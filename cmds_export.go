@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"taskman/app"
+
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write the tasks to a JSON file",
+	Long: `Write the tasks of the store, whatever its backend, to a plain JSON file
+in the format of the JSON store. Every task carries its UUID, so the file
+can be imported into another store with "taskman import".`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer closeStore(store)
+
+		output, _ := cmd.Flags().GetString("output")
+		if output == "" || output == "-" {
+			return app.Export(os.Stdout, store.List())
+		}
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		if err := app.Export(f, store.List()); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Add or update tasks from an export",
+	Long: `Import the tasks of an export, or of a plain JSON store file, matching
+them to the tasks of the store by UUID. New tasks are added, keeping their
+ID unless another task has it. Known tasks are updated if the file has the
+newer copy; tasks in the trash or the archive stay there.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		tasks, err := app.ReadExport(data)
+		if err != nil {
+			return err
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer closeStore(store)
+
+		report, err := app.ImportTasks(store, tasks)
+		if err != nil {
+			return err
+		}
+		for _, r := range report.Renumbered {
+			fmt.Fprintln(os.Stderr, "renumbered:", r)
+		}
+		fmt.Printf("Added %d tasks, updated %d, %d unchanged.\n", report.Added, report.Updated, report.Unchanged)
+		return nil
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringP("output", "o", "", "write to this file instead of stdout")
+	rootCmd.AddCommand(exportCmd, importCmd)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"taskman/app"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync [url]",
+	Short: "Pull, merge and push the tasks of a git-backed store",
+	Long: `With "git.enabled" set, the directory of the task store is a git
+repository and every change is committed. sync merges the changes of the
+"git.remote" remote and pushes the result back. Given a url, the remote is
+pointed at it first.`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !viper.GetBool("git.enabled") {
+			return fmt.Errorf("set \"git.enabled\" in your config to sync the task store")
+		}
+		opened, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer closeStore(opened)
+		store, ok := opened.(*app.Store)
+		if !ok {
+			return fmt.Errorf("sync is only supported by the %s backend", app.BackendJSON)
+		}
+
+		driver, err := mergeDriver()
+		if err != nil {
+			return err
+		}
+		if err := store.SetGitMergeDriver(driver); err != nil {
+			return err
+		}
+		remote := viper.GetString("git.remote")
+		if len(args) == 1 {
+			if err := store.SetGitRemote(remote, args[0]); err != nil {
+				return err
+			}
+		}
+		merged, err := store.Sync(remote)
+		if err != nil {
+			return err
+		}
+		if merged {
+			fmt.Printf("Synced with %s: merged new changes.\n", remote)
+		} else {
+			fmt.Printf("Synced with %s: nothing new to merge.\n", remote)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+}
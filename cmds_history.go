@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"taskman/app"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history <id>",
+	Short: "Show the changes made to a task",
+	Long: `Show when a task was created and every change made to it since: edits
+of its title, notes, tags, priority, project, day and due date, completion,
+deletion and restores.
+Tasks in the trash have their history too.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ids, err := parseIDs(args)
+		if err != nil {
+			return err
+		}
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer closeStore(store)
+
+		t, err := findTask(store, ids[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("#%d %s\n", t.ID, t.Title)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "WHEN\tFIELD\tFROM\tTO")
+		fmt.Fprintf(w, "%s\tcreated\t\t\n", t.CreatedAt.Format("2006-01-02 15:04"))
+		for _, c := range t.Changes {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.At.Format("2006-01-02 15:04"), c.Field, c.Old, c.New)
+		}
+		return w.Flush()
+	},
+}
+
+// findTask returns the task with the given ID from the store or its trash.
+func findTask(store app.TaskStore, id int) (*app.Task, error) {
+	t, err := store.Get(id)
+	if !errors.Is(err, app.ErrNotFound) {
+		return t, err
+	}
+	if trash, ok := store.(app.Trasher); ok {
+		for _, t := range trash.Trash() {
+			if t.ID == id {
+				return t, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("task %d: %w", id, err)
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+}
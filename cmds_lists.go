@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"taskman/app"
+
+	"github.com/spf13/cobra"
+)
+
+var listsCmd = &cobra.Command{
+	Use:   "lists",
+	Short: "Show the task lists, marking the active one",
+	Long: `Task lists are separate stores kept next to the task store file. Open
+one with --list <name> or set "list.default" in your config; a list that
+does not exist yet is created. Press L in the task view to switch lists.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names, err := app.Lists(baseStorePath())
+		if err != nil {
+			return err
+		}
+		active := activeList()
+		for _, name := range names {
+			mark := " "
+			if name == active {
+				mark = "*"
+			}
+			fmt.Println(mark, name)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listsCmd)
+}
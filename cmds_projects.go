@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"taskman/app"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var projectsCmd = &cobra.Command{
+	Use:   "projects",
+	Short: "Print the tree of projects with their open tasks",
+	Long: `Projects are dotted paths like "work.backend.billing"; a task in a
+project counts towards every project above it too. Without a subcommand,
+print the tree of projects with the number of open tasks in each. In the
+TUI, "p" moves to the projects pane.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer closeStore(store)
+
+		tree := app.ProjectTree(store.ListProject(""))
+		if len(tree) == 0 {
+			fmt.Println("No projects.")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PROJECT\tOPEN")
+		for _, n := range tree {
+			n.Walk(func(n *app.ProjectNode, depth int) bool {
+				fmt.Fprintf(w, "%s%s\t%d\n", strings.Repeat("  ", depth), n.Name, n.Open)
+				return true
+			})
+		}
+		return w.Flush()
+	},
+}
+
+var projectsSetCmd = &cobra.Command{
+	Use:          "set <id> [project]",
+	Short:        "Move a task to a project, or out of any if none is given",
+	Args:         cobra.RangeArgs(1, 2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ids, err := parseIDs(args[:1])
+		if err != nil {
+			return err
+		}
+		project := ""
+		if len(args) == 2 {
+			project = args[1]
+		}
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer closeStore(store)
+
+		t, err := store.Update(ids[0], app.UpdateOptions{Project: &project})
+		if err != nil {
+			return fmt.Errorf("update task %d: %w", ids[0], err)
+		}
+		if t.Project == "" {
+			fmt.Printf("#%d %s is in no project.\n", t.ID, t.Title)
+			return nil
+		}
+		fmt.Printf("#%d %s is in %s.\n", t.ID, t.Title, t.Project)
+		return nil
+	},
+}
+
+func init() {
+	projectsCmd.AddCommand(projectsSetCmd)
+	rootCmd.AddCommand(projectsCmd)
+}
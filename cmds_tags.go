@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"taskman/app"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var tagsCmd = &cobra.Command{
+	Use:   "tags",
+	Short: "List tags and tag or untag tasks",
+	Long: `Tags are short labels on tasks, e.g. "work" or "errands". They are
+lowercase and may be written with a leading '#'. In the TUI, "t" shows only
+the tasks with a given tag.`,
+}
+
+var tagsListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List the tags in use and how many tasks carry each",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer closeStore(store)
+
+		counts := app.CountTags(store.List())
+		if len(counts) == 0 {
+			fmt.Println("No tags.")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TAG\tTASKS")
+		for _, tc := range counts {
+			fmt.Fprintf(w, "#%s\t%d\n", tc.Tag, tc.Tasks)
+		}
+		return w.Flush()
+	},
+}
+
+var tagsAddCmd = &cobra.Command{
+	Use:          "add <id> <tag>...",
+	Short:        "Add tags to a task",
+	Args:         cobra.MinimumNArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return retag(args, func(tags, given []string) []string {
+			return append(tags, given...)
+		})
+	},
+}
+
+var tagsRemoveCmd = &cobra.Command{
+	Use:          "remove <id> [tag]...",
+	Short:        "Remove tags from a task, or all of them if none are given",
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return retag(args, func(tags, given []string) []string {
+			if len(given) == 0 {
+				return nil
+			}
+			return slices.DeleteFunc(tags, func(tag string) bool { return slices.Contains(given, tag) })
+		})
+	},
+}
+
+// retag replaces the tags of the task args[0] with what fn makes of them
+// and the tags in args[1:].
+func retag(args []string, fn func(tags, given []string) []string) error {
+	ids, err := parseIDs(args[:1])
+	if err != nil {
+		return err
+	}
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer closeStore(store)
+
+	t, err := store.Get(ids[0])
+	if err != nil {
+		return fmt.Errorf("task %d: %w", ids[0], err)
+	}
+	tags := fn(slices.Clone(t.Tags), app.NormalizeTags(args[1:]))
+	if t, err = store.Update(t.ID, app.UpdateOptions{Tags: &tags}); err != nil {
+		return fmt.Errorf("update task %d: %w", ids[0], err)
+	}
+	if len(t.Tags) == 0 {
+		fmt.Printf("#%d %s has no tags.\n", t.ID, t.Title)
+		return nil
+	}
+	fmt.Printf("#%d %s: #%s\n", t.ID, t.Title, strings.Join(t.Tags, " #"))
+	return nil
+}
+
+func init() {
+	tagsCmd.AddCommand(tagsListCmd, tagsAddCmd, tagsRemoveCmd)
+	rootCmd.AddCommand(tagsCmd)
+}
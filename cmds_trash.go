@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"taskman/app"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "List, restore and empty deleted tasks",
+	Long: `Deleted tasks are kept in the trash for "trash.retention_days" days
+before they are purged for good. These commands manage the trash by hand.`,
+}
+
+var trashListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List deleted tasks, most recently deleted first",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		trash, closeFn, err := openTrash()
+		if err != nil {
+			return err
+		}
+		defer closeFn()
+
+		tasks := trash.Trash()
+		if len(tasks) == 0 {
+			fmt.Println("Trash is empty.")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tDELETED\tDAY\tTITLE")
+		for _, t := range tasks {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", t.ID, t.DeletedAt.Format("2006-01-02 15:04"), t.Date.Format("2006-01-02"), t.Title)
+		}
+		return w.Flush()
+	},
+}
+
+var trashRestoreCmd = &cobra.Command{
+	Use:          "restore <id>...",
+	Short:        "Move deleted tasks back into the task list",
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ids, err := parseIDs(args)
+		if err != nil {
+			return err
+		}
+		trash, closeFn, err := openTrash()
+		if err != nil {
+			return err
+		}
+		defer closeFn()
+
+		for _, id := range ids {
+			t, err := trash.Restore(id)
+			if err != nil {
+				return fmt.Errorf("restore task %d: %w", id, err)
+			}
+			fmt.Printf("Restored #%d %s.\n", t.ID, t.Title)
+		}
+		return nil
+	},
+}
+
+var trashEmptyCmd = &cobra.Command{
+	Use:          "empty",
+	Short:        "Purge deleted tasks for good",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		days, _ := cmd.Flags().GetInt("older-than")
+		trash, closeFn, err := openTrash()
+		if err != nil {
+			return err
+		}
+		defer closeFn()
+
+		n, err := trash.PurgeTrash(time.Now().AddDate(0, 0, -days))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Purged %d tasks.\n", n)
+		return nil
+	},
+}
+
+// openTrash opens the configured store and returns its trash, along with a
+// function that closes the store.
+func openTrash() (app.Trasher, func(), error) {
+	store, err := openStore()
+	if err != nil {
+		return nil, nil, err
+	}
+	trash, ok := store.(app.Trasher)
+	if !ok {
+		closeStore(store)
+		return nil, nil, fmt.Errorf("store does not keep a trash")
+	}
+	return trash, func() { closeStore(store) }, nil
+}
+
+// parseIDs converts task ID arguments to ints.
+func parseIDs(args []string) ([]int, error) {
+	ids := make([]int, 0, len(args))
+	for _, arg := range args {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid task ID %q", arg)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func init() {
+	trashEmptyCmd.Flags().Int("older-than", 0, "only purge tasks deleted at least this many days ago")
+	trashCmd.AddCommand(trashListCmd, trashRestoreCmd, trashEmptyCmd)
+	rootCmd.AddCommand(trashCmd)
+}
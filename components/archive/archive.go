@@ -0,0 +1,182 @@
+package archive
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"taskman/app"
+	"taskman/components/config"
+	"taskman/components/overlay"
+	"taskman/utils"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	general = lipgloss.NewStyle().
+		Padding(0, 1, 0, 1).
+		Foreground(config.COLOR_FOREGROUND).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(config.COLOR_HIGHLIGHT)
+
+	selectedStyle = lipgloss.NewStyle().Background(config.COLOR_HIGHLIGHT).Foreground(config.COLOR_FOREGROUND).Bold(true)
+	dateStyle     = lipgloss.NewStyle().Foreground(config.COLOR_LIGHTER)
+	helpStyle     = lipgloss.NewStyle().Foreground(config.COLOR_GRAY).MarginTop(1)
+)
+
+// maxRows is how many tasks of a year are shown at once.
+const maxRows = 15
+
+// ClosedMsg is sent when the archive view is closed. Day is the day to
+// show next, zero to stay on the current one. Restored tasks are not
+// reported one by one, so the parent should refresh on it.
+type ClosedMsg struct {
+	Day time.Time
+}
+
+// View is a popup browsing the archived tasks one year at a time.
+type View struct {
+	archive app.Archiver
+	years   []int
+	year    int // index in years
+	tasks   []*app.Task
+	cursor  int
+	err     error
+	bgRaw   string
+	width   int
+}
+
+// New creates an archive view over bgRaw, showing the newest year.
+func New(archive app.Archiver, bgRaw string, width int) View {
+	v := View{archive: archive, bgRaw: bgRaw, width: width}
+	v.refresh()
+	return v
+}
+
+// Init initializes the popup.
+func (v View) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages.
+func (v View) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case app.StoreChangedMsg, app.EventsMsg:
+		v.refresh()
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc", "q", "A":
+			return v, func() tea.Msg { return ClosedMsg{} }
+		case "left", "h":
+			if v.year < len(v.years)-1 {
+				v.year, v.cursor = v.year+1, 0
+				v.loadYear()
+			}
+		case "right", "l":
+			if v.year > 0 {
+				v.year, v.cursor = v.year-1, 0
+				v.loadYear()
+			}
+		case "up", "k":
+			if v.cursor > 0 {
+				v.cursor--
+			}
+		case "down", "j":
+			if v.cursor < len(v.tasks)-1 {
+				v.cursor++
+			}
+		case "enter":
+			if t := v.selected(); t != nil {
+				return v, func() tea.Msg { return ClosedMsg{Day: t.Date} }
+			}
+		case "r":
+			if t := v.selected(); t != nil {
+				_, v.err = v.archive.Unarchive(t.ID)
+				v.refresh()
+				if v.err == nil {
+					return v, app.Status(fmt.Sprintf("restored %q", t.Title))
+				}
+			}
+		}
+	}
+	return v, nil
+}
+
+// View renders the popup.
+func (v View) View() string {
+	width := v.width - 4
+	var b strings.Builder
+	if len(v.tasks) == 0 {
+		b.WriteString(config.EmptyMessageStyle.Render("Archive is empty."))
+	}
+	// Scroll so the cursor stays in sight.
+	first := utils.MaxInt(v.cursor-maxRows+1, 0)
+	last := utils.MinInt(first+maxRows, len(v.tasks))
+	for i := first; i < last; i++ {
+		t := v.tasks[i]
+		date := dateStyle.Render(t.Date.Format("Mon, Jan 2"))
+		title := utils.Truncate(t.Title, utils.MaxInt(width-lipgloss.Width(date)-4, 10))
+		padding := utils.MaxInt(width-lipgloss.Width(title)-lipgloss.Width(date)-2, 1)
+		line := " " + title + strings.Repeat(" ", padding) + date + " "
+		if i == v.cursor {
+			line = selectedStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+	if v.err != nil {
+		b.WriteString("\n" + config.ErrorStyle.Render("Error: "+v.err.Error()) + "\n")
+	}
+
+	title := "Archive"
+	if v.year < len(v.years) {
+		title = fmt.Sprintf("Archive %d (%d)", v.years[v.year], len(v.tasks))
+	}
+	header := config.BoxHeader.Width(width).Render(title)
+	help := helpStyle.Render("←/→ year • enter go to day • r restore • esc close")
+	ui := lipgloss.JoinVertical(lipgloss.Left, header, " ", b.String(), help)
+	return overlay.PlaceCenter(general.Width(width).Render(ui), v.bgRaw)
+}
+
+// refresh reads the archived years again and stays on the current year
+// while it still has tasks.
+func (v *View) refresh() {
+	var current int
+	if v.year < len(v.years) {
+		current = v.years[v.year]
+	}
+	years, err := v.archive.ArchiveYears()
+	if err != nil {
+		v.err = err
+	}
+	v.years, v.year = years, 0
+	for i, year := range years {
+		if year == current {
+			v.year = i
+		}
+	}
+	v.loadYear()
+}
+
+func (v *View) loadYear() {
+	v.tasks = nil
+	if v.year < len(v.years) {
+		tasks, err := v.archive.Archived(v.years[v.year])
+		if err != nil {
+			v.err = err
+		}
+		v.tasks = tasks
+	}
+	if v.cursor >= len(v.tasks) {
+		v.cursor = utils.MaxInt(len(v.tasks)-1, 0)
+	}
+}
+
+func (v View) selected() *app.Task {
+	if v.cursor < len(v.tasks) {
+		return v.tasks[v.cursor]
+	}
+	return nil
+}
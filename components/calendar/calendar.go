@@ -23,6 +23,7 @@ var taskContainerStyle = lipgloss.NewStyle().Padding(0, 2)
 
 type model struct {
 	day    time.Time
+	list   string // name of the active task list
 	dp     datepicker.Model
 	width  int
 	height int
@@ -42,22 +43,30 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.day = msg.Day
 		m.dp.SetTime(m.day)
 
+	case app.ListSwitchedMsg:
+		m.list = msg.Name
+
 	}
 	return m, nil
 }
 
 func (m model) View() string {
 	return baseStyle.Width(m.width - 2).Height(m.height - 2).Render(
-		m.dp.View(),
+		lipgloss.JoinVertical(lipgloss.Center,
+			subHeader.Render("󰉹 "+m.list),
+			m.dp.View(),
+		),
 	)
 }
 
-func New() *model {
+// New creates the calendar pane for the named task list.
+func New(list string) *model {
 	dpView := datepicker.New(time.Now())
 	dpView.SelectDate()
 
 	m := &model{
-		dp: dpView,
+		list: list,
+		dp:   dpView,
 	}
 	return m
 }
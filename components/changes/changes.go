@@ -0,0 +1,121 @@
+package changes
+
+import (
+	"fmt"
+	"strings"
+
+	"taskman/app"
+	"taskman/components/config"
+	"taskman/components/overlay"
+	"taskman/utils"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	general = lipgloss.NewStyle().
+		Padding(0, 1, 0, 1).
+		Foreground(config.COLOR_FOREGROUND).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(config.COLOR_HIGHLIGHT)
+
+	dateStyle = lipgloss.NewStyle().Foreground(config.COLOR_LIGHTER)
+	helpStyle = lipgloss.NewStyle().Foreground(config.COLOR_GRAY).MarginTop(1)
+)
+
+// maxRows is how many changes are shown at once.
+const maxRows = 15
+
+// ClosedMsg is sent when the history view is closed.
+type ClosedMsg struct{}
+
+// View is a popup listing the history of a task, oldest change first.
+type View struct {
+	store  app.TaskStore
+	task   *app.Task
+	offset int // index of the first change shown
+	err    error
+	bgRaw  string
+	width  int
+}
+
+// New creates a history view of task over bgRaw, scrolled to the latest
+// changes.
+func New(store app.TaskStore, task *app.Task, bgRaw string, width int) View {
+	v := View{store: store, task: task, bgRaw: bgRaw, width: width}
+	v.offset = v.maxOffset()
+	return v
+}
+
+// Init initializes the popup.
+func (v View) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages.
+func (v View) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case app.StoreChangedMsg, app.EventsMsg:
+		// A deleted task keeps showing what it was.
+		if t, err := v.store.Get(v.task.ID); err == nil {
+			atEnd := v.offset == v.maxOffset()
+			v.task = t
+			if atEnd {
+				v.offset = v.maxOffset()
+			}
+		}
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc", "q", "H":
+			return v, func() tea.Msg { return ClosedMsg{} }
+		case "up", "k":
+			if v.offset > 0 {
+				v.offset--
+			}
+		case "down", "j":
+			if v.offset < v.maxOffset() {
+				v.offset++
+			}
+		}
+	}
+	return v, nil
+}
+
+// View renders the popup.
+func (v View) View() string {
+	width := v.width - 4
+	var b strings.Builder
+	lines := v.lines(width)
+	last := utils.MinInt(v.offset+maxRows, len(lines))
+	for _, line := range lines[v.offset:last] {
+		b.WriteString(line + "\n")
+	}
+	if v.err != nil {
+		b.WriteString("\n" + config.ErrorStyle.Render("Error: "+v.err.Error()) + "\n")
+	}
+
+	title := utils.Truncate(v.task.Title, utils.MaxInt(width-20, 10))
+	header := config.BoxHeader.Width(width).Render(fmt.Sprintf("History of %q (%d)", title, len(v.task.Changes)))
+	help := helpStyle.Render("↑/↓ scroll • esc close")
+	ui := lipgloss.JoinVertical(lipgloss.Left, header, " ", b.String(), help)
+	return overlay.PlaceCenter(general.Width(width).Render(ui), v.bgRaw)
+}
+
+// lines returns a line for the creation of the task and one per change.
+func (v View) lines(width int) []string {
+	line := func(at, text string) string {
+		date := dateStyle.Render(at)
+		return " " + date + "  " + utils.Truncate(text, utils.MaxInt(width-lipgloss.Width(date)-4, 10))
+	}
+	out := []string{line(v.task.CreatedAt.Format("2006-01-02 15:04"), "created")}
+	for _, c := range v.task.Changes {
+		out = append(out, line(c.At.Format("2006-01-02 15:04"), c.Field+": "+c.Old+" → "+c.New))
+	}
+	return out
+}
+
+func (v View) maxOffset() int {
+	return utils.MaxInt(len(v.task.Changes)+1-maxRows, 0)
+}
@@ -1,6 +1,10 @@
 package config
 
 import (
+	"hash/fnv"
+
+	"taskman/app"
+
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -37,6 +41,64 @@ var methodColors = map[string]string{
 	PATCH:  "#6C9EF8",
 }
 
+// tagPalette colours the tags that have no colour of their own in
+// tagColors; a tag always gets the same one.
+var tagPalette = []string{"#43BF6D", "#FFB454", "#6C9EF8", "#F25C54", "#C792EA", "#4FD6BE", "#F2C94C"}
+
+var tagColors = map[string]string{}
+
+// SetTagColors sets the colours of tags, by name, from the tags.colors
+// setting.
+func SetTagColors(colors map[string]string) {
+	tagColors = colors
+}
+
+// TagStyle returns the style of the chip of a tag.
+func TagStyle(tag string) lipgloss.Style {
+	color, ok := tagColors[tag]
+	if !ok {
+		h := fnv.New32a()
+		h.Write([]byte(tag))
+		color = tagPalette[h.Sum32()%uint32(len(tagPalette))]
+	}
+	return lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#1a1a1a")).
+		Background(lipgloss.Color(color)).
+		Padding(0, 1)
+}
+
+// priorityMarks are the glyphs shown next to the titles of tasks, by
+// priority; tasks without one get none.
+var priorityMarks = map[app.Priority]string{
+	app.PriorityLow:      "↓",
+	app.PriorityMedium:   "•",
+	app.PriorityHigh:     "↑",
+	app.PriorityCritical: "‼",
+}
+
+var priorityColors = map[app.Priority]lipgloss.TerminalColor{
+	app.PriorityNone:     COLOR_LIGHTER,
+	app.PriorityLow:      COLOR_LINK,
+	app.PriorityMedium:   COLOR_SPECIAL,
+	app.PriorityHigh:     COLOR_WARNING,
+	app.PriorityCritical: COLOR_ERROR,
+}
+
+// PriorityStyle returns the style of a priority.
+func PriorityStyle(p app.Priority) lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(priorityColors[p]).Bold(p >= app.PriorityHigh)
+}
+
+// PriorityMark renders the glyph of a priority, or a blank of the same
+// width, so titles line up.
+func PriorityMark(p app.Priority) string {
+	mark, ok := priorityMarks[p]
+	if !ok {
+		return " "
+	}
+	return PriorityStyle(p).Render(mark)
+}
+
 var BoxHeader = lipgloss.NewStyle().
 	BorderStyle(lipgloss.NormalBorder()).
 	BorderBottom(true).
@@ -94,11 +156,20 @@ type WindowFocusedMsg struct {
 }
 
 type KeyMap struct {
-	Up     key.Binding
-	Down   key.Binding
-	Toggle key.Binding
-	Delete key.Binding
-	Quit   key.Binding
+	Up       key.Binding
+	Down     key.Binding
+	Toggle   key.Binding
+	Delete   key.Binding
+	Undo     key.Binding
+	Redo     key.Binding
+	Trash    key.Binding
+	Archive  key.Binding
+	History  key.Binding
+	Tags     key.Binding
+	Priority key.Binding
+	Projects key.Binding
+	Lists    key.Binding
+	Quit     key.Binding
 }
 
 func SetVersion(v string) {
@@ -111,12 +182,12 @@ func GetVersion() string {
 
 func (k KeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.Up, k.Down, k.Toggle, k.Delete, k.Quit},
+		{k.Up, k.Down, k.Toggle, k.Delete, k.Undo, k.Redo, k.Trash, k.Archive, k.History, k.Tags, k.Priority, k.Projects, k.Lists, k.Quit},
 	}
 }
 
 func (k KeyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Up, k.Down, k.Toggle, k.Delete, k.Quit}
+	return []key.Binding{k.Up, k.Down, k.Toggle, k.Delete, k.Undo, k.Redo, k.Trash, k.Archive, k.History, k.Tags, k.Priority, k.Projects, k.Lists, k.Quit}
 }
 
 var Keys = KeyMap{
@@ -136,6 +207,42 @@ var Keys = KeyMap{
 		key.WithKeys("d"),
 		key.WithHelp("d", "delete"),
 	),
+	Undo: key.NewBinding(
+		key.WithKeys("u"),
+		key.WithHelp("u", "undo"),
+	),
+	Redo: key.NewBinding(
+		key.WithKeys("ctrl+r"),
+		key.WithHelp("ctrl+r", "redo"),
+	),
+	Trash: key.NewBinding(
+		key.WithKeys("T"),
+		key.WithHelp("T", "trash"),
+	),
+	Archive: key.NewBinding(
+		key.WithKeys("A"),
+		key.WithHelp("A", "archive"),
+	),
+	History: key.NewBinding(
+		key.WithKeys("H"),
+		key.WithHelp("H", "history"),
+	),
+	Tags: key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("t", "tags"),
+	),
+	Priority: key.NewBinding(
+		key.WithKeys("+", "-"),
+		key.WithHelp("+/-", "priority"),
+	),
+	Projects: key.NewBinding(
+		key.WithKeys("p"),
+		key.WithHelp("p", "projects"),
+	),
+	Lists: key.NewBinding(
+		key.WithKeys("L"),
+		key.WithHelp("L", "lists"),
+	),
 	Quit: key.NewBinding(
 		key.WithKeys("ctrl+c"),
 		key.WithHelp("ctr+c", "quit"),
@@ -1,7 +1,11 @@
 package footer
 
 import (
+	"time"
+
+	"taskman/app"
 	"taskman/components/config"
+	"taskman/utils"
 
 	"github.com/charmbracelet/bubbles/help"
 	tea "github.com/charmbracelet/bubbletea"
@@ -12,13 +16,23 @@ var (
 	container    = lipgloss.NewStyle()
 	versionStyle = lipgloss.NewStyle().Foreground(config.COLOR_HIGHLIGHT)
 	nameStyle    = lipgloss.NewStyle().Foreground(config.COLOR_HIGHLIGHT).Underline(true)
+	statusStyle  = lipgloss.NewStyle().Foreground(config.COLOR_LIGHTER).Italic(true)
 )
 
+// statusTimeout is how long a status notice stays in the footer.
+const statusTimeout = 4 * time.Second
+
+// statusExpiredMsg only wakes the program up so the footer is redrawn
+// once a notice has expired.
+type statusExpiredMsg struct{}
+
 // model represents the properties of the UI.
 type model struct {
 	height int
 	width  int
 	help   help.Model
+	status string
+	until  time.Time
 }
 
 // New creates a new instance of the UI.
@@ -37,6 +51,13 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.height = msg.Height
 		m.width = msg.Width
+
+	case app.StatusMsg:
+		m.status = msg.Text
+		m.until = time.Now().Add(statusTimeout)
+		return m, tea.Tick(statusTimeout, func(time.Time) tea.Msg {
+			return statusExpiredMsg{}
+		})
 	}
 
 	return m, nil
@@ -45,7 +66,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 // View returns a string representation of the UI.
 func (m model) View() string {
 
-	helpView := m.help.View(config.Keys)
+	name := nameStyle.Render("TASKMAN") + versionStyle.Render(" v."+config.GetVersion())
+	room := m.width - lipgloss.Width(name) - 3
+	var helpView string
+	if m.status != "" && time.Now().Before(m.until) {
+		// A notice takes the place of the help line while it is shown
+		helpView = statusStyle.Render(utils.Truncate(m.status, utils.MaxInt(room, 10)))
+	} else {
+		// Bindings that do not fit next to the name are cut off
+		m.help.Width = room
+		helpView = m.help.View(config.Keys)
+	}
 
 	statusWidth := lipgloss.Width(helpView) + 1
 
@@ -56,9 +87,7 @@ func (m model) View() string {
 			lipgloss.PlaceHorizontal(
 				m.width-statusWidth-1,
 				lipgloss.Right,
-				nameStyle.Render("TASKMAN")+
-
-					versionStyle.Render(" v."+config.GetVersion()),
+				name,
 			),
 		),
 	)
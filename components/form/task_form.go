@@ -24,21 +24,28 @@ var (
 
 const (
 	TITLE_IDX = iota
+	TAGS_IDX
+	PROJECT_IDX
+	PRIORITY_IDX
 	TEXTAREA_IDX
 	CLOSE_IDX
 	SAVE_IDX
+	fieldCount
 )
 
 type TaskForm struct {
-	titleInput textinput.Model
-	notesInput textarea.Model
-	focused    int
-	save       bool
-	errors     []string
-	bgRaw      string
-	width      int
-	startRow   int
-	startCol   int
+	titleInput   textinput.Model
+	tagsInput    textinput.Model
+	projectInput textinput.Model
+	notesInput   textarea.Model
+	priority     app.Priority
+	focused      int
+	save         bool
+	errors       []string
+	bgRaw        string
+	width        int
+	startRow     int
+	startCol     int
 }
 
 func NewTaskForm(bgRaw string, width int, vWidth int) TaskForm {
@@ -47,17 +54,27 @@ func NewTaskForm(bgRaw string, width int, vWidth int) TaskForm {
 	titleInput.Placeholder = "Title"
 	titleInput.Prompt = "󱞩 "
 
+	tagsInput := textinput.New()
+	tagsInput.Placeholder = "work, home"
+	tagsInput.Prompt = "# "
+
+	projectInput := textinput.New()
+	projectInput.Placeholder = "work.backend"
+	projectInput.Prompt = "󰉋 "
+
 	textArea := textarea.New()
 	textArea.ShowLineNumbers = false
 
 	return TaskForm{
-		bgRaw:      bgRaw,
-		startRow:   3,
-		width:      width,
-		startCol:   vWidth - width - 4,
-		titleInput: titleInput,
-		notesInput: textArea,
-		focused:    TITLE_IDX,
+		bgRaw:        bgRaw,
+		startRow:     3,
+		width:        width,
+		startCol:     vWidth - width - 4,
+		titleInput:   titleInput,
+		tagsInput:    tagsInput,
+		projectInput: projectInput,
+		notesInput:   textArea,
+		focused:      TITLE_IDX,
 	}
 }
 
@@ -69,6 +86,23 @@ func (c TaskForm) Notes() string {
 	return c.notesInput.Value()
 }
 
+func (c TaskForm) Tags() []string {
+	return app.ParseTags(c.tagsInput.Value())
+}
+
+func (c TaskForm) Project() string {
+	return app.NormalizeProject(c.projectInput.Value())
+}
+
+// SetProject fills in the project, e.g. the one the task list is scoped to.
+func (c *TaskForm) SetProject(path string) {
+	c.projectInput.SetValue(path)
+}
+
+func (c TaskForm) Priority() app.Priority {
+	return c.priority
+}
+
 // Init initializes the popup.
 func (c TaskForm) Init() tea.Cmd {
 	return textinput.Blink
@@ -76,7 +110,7 @@ func (c TaskForm) Init() tea.Cmd {
 
 // nextInput focuses the next input field
 func (c *TaskForm) nextInput() {
-	c.focused = (c.focused + 1) % 4
+	c.focused = (c.focused + 1) % fieldCount
 }
 
 // prevInput focuses the previous input field
@@ -84,7 +118,7 @@ func (c *TaskForm) prevInput() {
 	c.focused--
 	// Wrap around
 	if c.focused < 0 {
-		c.focused = 4
+		c.focused = fieldCount - 1
 	}
 }
 
@@ -109,12 +143,28 @@ func (c TaskForm) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case tea.KeyTab, tea.KeyCtrlJ:
 			c.nextInput()
 		}
+		if c.focused == PRIORITY_IDX {
+			switch msg.String() {
+			case "right", "l", "+":
+				c.priority = c.priority.Raise()
+			case "left", "h", "-":
+				c.priority = c.priority.Lower()
+			}
+		}
 
 		c.titleInput.Blur()
+		c.tagsInput.Blur()
+		c.projectInput.Blur()
 		c.notesInput.Blur()
 		if c.focused == TITLE_IDX {
 			c.titleInput.Focus()
 			c.titleInput, cmds[0] = c.titleInput.Update(msg)
+		} else if c.focused == TAGS_IDX {
+			c.tagsInput.Focus()
+			c.tagsInput, cmds[0] = c.tagsInput.Update(msg)
+		} else if c.focused == PROJECT_IDX {
+			c.projectInput.Focus()
+			c.projectInput, cmds[0] = c.projectInput.Update(msg)
 		} else if c.focused == TEXTAREA_IDX {
 			c.notesInput.Focus()
 			c.notesInput, cmds[0] = c.notesInput.Update(msg)
@@ -159,6 +209,15 @@ func (c TaskForm) View() string {
 		config.LabelStyle.Width(30).Render("Title:"),
 		config.InputStyle.Render(c.titleInput.View()),
 		" ",
+		config.LabelStyle.Width(30).Render("Tags:"),
+		config.InputStyle.Render(c.tagsInput.View()),
+		" ",
+		config.LabelStyle.Width(30).Render("Project:"),
+		config.InputStyle.Render(c.projectInput.View()),
+		" ",
+		config.LabelStyle.Width(30).Render("Priority:"),
+		c.priorityView(),
+		" ",
 		config.LabelStyle.Width(30).Render("Notes:"),
 		config.InputStyle.Render(c.notesInput.View()),
 		" ",
@@ -172,12 +231,29 @@ func (c TaskForm) View() string {
 	return overlay.PlaceCenter(content, c.bgRaw)
 }
 
+// priorityView renders the priorities side by side, the chosen one
+// highlighted while the field has the focus.
+func (c TaskForm) priorityView() string {
+	var items []string
+	for _, p := range app.Priorities {
+		item := config.PriorityStyle(p).Padding(0, 1)
+		if p == c.priority {
+			item = item.Reverse(c.focused == PRIORITY_IDX).Underline(c.focused != PRIORITY_IDX)
+		}
+		items = append(items, item.Render(p.String()))
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, items...)
+}
+
 func (c TaskForm) makeChoice() tea.Cmd {
 	return func() tea.Msg {
 		return app.TaskFormResultMsg{
-			Result: c.save,
-			Title:  c.Title(),
-			Notes:  c.Notes(),
+			Result:   c.save,
+			Title:    c.Title(),
+			Notes:    c.Notes(),
+			Tags:     c.Tags(),
+			Priority: c.Priority(),
+			Project:  c.Project(),
 		}
 	}
 }
@@ -0,0 +1,147 @@
+package lists
+
+import (
+	"fmt"
+	"strings"
+
+	"taskman/app"
+	"taskman/components/config"
+	"taskman/components/overlay"
+	"taskman/utils"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	general = lipgloss.NewStyle().
+		Padding(0, 1, 0, 1).
+		Foreground(config.COLOR_FOREGROUND).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(config.COLOR_HIGHLIGHT)
+
+	selectedStyle = lipgloss.NewStyle().Background(config.COLOR_HIGHLIGHT).Foreground(config.COLOR_FOREGROUND).Bold(true)
+	activeStyle   = lipgloss.NewStyle().Foreground(config.COLOR_LIGHTER)
+	helpStyle     = lipgloss.NewStyle().Foreground(config.COLOR_GRAY).MarginTop(1)
+)
+
+// ClosedMsg is sent when the picker is closed without choosing a list.
+type ClosedMsg struct{}
+
+// Picker is a popup listing the task lists. Choosing one sends an
+// app.ListSelectedMsg; "n" asks for the name of a new list instead.
+type Picker struct {
+	names  []string
+	active string
+	cursor int
+	input  textinput.Model
+	adding bool
+	err    error
+	bgRaw  string
+	width  int
+}
+
+// New creates a list picker over bgRaw with the cursor on the active list.
+func New(names []string, active string, bgRaw string, width int) Picker {
+	input := textinput.New()
+	input.Placeholder = "List name"
+	input.Prompt = "󰐕 "
+
+	p := Picker{names: names, active: active, input: input, bgRaw: bgRaw, width: width}
+	for i, name := range names {
+		if name == active {
+			p.cursor = i
+		}
+	}
+	return p
+}
+
+// Init initializes the popup.
+func (p Picker) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages.
+func (p Picker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return p, nil
+	}
+
+	if p.adding {
+		switch key.String() {
+		case "esc":
+			p.adding, p.err = false, nil
+			p.input.Blur()
+			return p, nil
+		case "enter":
+			name := strings.TrimSpace(p.input.Value())
+			if err := app.ValidateListName(name); err != nil {
+				p.err = err
+				return p, nil
+			}
+			return p, selected(name)
+		}
+		var cmd tea.Cmd
+		p.input, cmd = p.input.Update(key)
+		return p, cmd
+	}
+
+	switch key.String() {
+	case "esc", "q", "L":
+		return p, func() tea.Msg { return ClosedMsg{} }
+	case "up", "k":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case "down", "j":
+		if p.cursor < len(p.names)-1 {
+			p.cursor++
+		}
+	case "enter":
+		if p.cursor < len(p.names) {
+			return p, selected(p.names[p.cursor])
+		}
+	case "n":
+		p.adding = true
+		p.input.Reset()
+		return p, p.input.Focus()
+	}
+	return p, nil
+}
+
+func selected(name string) tea.Cmd {
+	return func() tea.Msg { return app.ListSelectedMsg{Name: name} }
+}
+
+// View renders the popup.
+func (p Picker) View() string {
+	width := p.width - 4
+	var b strings.Builder
+	for i, name := range p.names {
+		line := " " + utils.Truncate(name, utils.MaxInt(width-12, 10))
+		if name == p.active {
+			line += activeStyle.Render(" (active)")
+		}
+		line += strings.Repeat(" ", utils.MaxInt(width-lipgloss.Width(line)-2, 1))
+		if i == p.cursor && !p.adding {
+			line = selectedStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+	if p.adding {
+		b.WriteString("\n" + config.InputStyle.Render(p.input.View()) + "\n")
+	}
+	if p.err != nil {
+		b.WriteString("\n" + config.ErrorStyle.Render("Error: "+p.err.Error()) + "\n")
+	}
+
+	header := config.BoxHeader.Width(width).Render(fmt.Sprintf("Lists (%d)", len(p.names)))
+	help := helpStyle.Render("enter switch • n new list • esc close")
+	if p.adding {
+		help = helpStyle.Render("enter create • esc cancel")
+	}
+	ui := lipgloss.JoinVertical(lipgloss.Left, header, " ", b.String(), help)
+	return overlay.PlaceCenter(general.Width(width).Render(ui), p.bgRaw)
+}
@@ -0,0 +1,102 @@
+package passphrase
+
+import (
+	"errors"
+
+	"taskman/app"
+	"taskman/components/config"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var general = lipgloss.NewStyle().
+	Padding(0, 1, 0, 1).
+	Foreground(config.COLOR_FOREGROUND).
+	Border(lipgloss.RoundedBorder()).
+	BorderForeground(config.COLOR_HIGHLIGHT)
+
+const boxWidth = 50
+
+// Prompt is a popup asking for the passphrase of an encrypted task store.
+// It is run as its own program before the main UI starts and quits once
+// unlock accepts a passphrase or the user gives up.
+type Prompt struct {
+	input  textinput.Model
+	unlock func(passphrase []byte) error
+	err    error
+	width  int
+	height int
+}
+
+// New creates a passphrase prompt. unlock is called with every entered
+// passphrase until it returns nil.
+func New(unlock func(passphrase []byte) error) Prompt {
+	input := textinput.New()
+	input.Placeholder = "Passphrase"
+	input.Prompt = "󰌾 "
+	input.EchoMode = textinput.EchoPassword
+	input.Focus()
+
+	return Prompt{input: input, unlock: unlock}
+}
+
+// Init initializes the popup.
+func (p Prompt) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+// Update handles messages.
+func (p Prompt) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		p.width = msg.Width
+		p.height = msg.Height
+		return p, nil
+
+	case tea.KeyMsg:
+		switch msg.Type {
+		case tea.KeyCtrlC, tea.KeyEsc:
+			return p, tea.Quit
+		case tea.KeyEnter:
+			if p.input.Value() == "" {
+				return p, nil
+			}
+			if err := p.unlock([]byte(p.input.Value())); err != nil {
+				p.err = err
+				p.input.Reset()
+				return p, nil
+			}
+			return p, tea.Quit
+		}
+	}
+
+	var cmd tea.Cmd
+	p.input, cmd = p.input.Update(msg)
+	return p, cmd
+}
+
+// View renders the popup.
+func (p Prompt) View() string {
+	message := config.LabelStyle.Render("The task store is encrypted.")
+	if p.err != nil {
+		text := p.err.Error()
+		if errors.Is(p.err, app.ErrWrongKey) {
+			text = "Wrong passphrase, try again."
+		}
+		message = config.ErrorStyle.Render(text)
+	}
+
+	ui := lipgloss.JoinVertical(
+		lipgloss.Left,
+		config.BoxHeader.Width(boxWidth-4).Render("Unlock Taskman"),
+		" ",
+		message,
+		" ",
+		config.InputStyle.Render(p.input.View()),
+		" ",
+		config.LabelStyle.Render("enter unlock • esc quit"),
+	)
+	return lipgloss.Place(p.width, p.height, lipgloss.Center, lipgloss.Center, general.Width(boxWidth).Render(ui))
+}
@@ -0,0 +1,247 @@
+package projects
+
+import (
+	"fmt"
+	"strings"
+
+	"taskman/app"
+	"taskman/components/config"
+	"taskman/utils"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	baseStyle = lipgloss.NewStyle().
+			BorderStyle(lipgloss.NormalBorder()).
+			BorderForeground(lipgloss.Color("240"))
+
+	subHeader     = lipgloss.NewStyle().Foreground(config.COLOR_LIGHTER).Bold(true).Padding(1, 0)
+	cursorStyle   = lipgloss.NewStyle().Background(config.COLOR_HIGHLIGHT).Foreground(config.COLOR_FOREGROUND).Bold(true)
+	selectedStyle = lipgloss.NewStyle().Foreground(config.COLOR_HIGHLIGHT).Bold(true)
+	countStyle    = lipgloss.NewStyle().Foreground(config.COLOR_LIGHTER)
+	emptyStyle    = lipgloss.NewStyle().Foreground(config.COLOR_LIGHTER).Italic(true)
+)
+
+// FocusMsg moves the keyboard focus to the projects pane, or back to the
+// task list. The pane only handles keys while it has the focus.
+type FocusMsg struct {
+	Focused bool
+}
+
+// row is a project shown in the pane; a nil node is the "All tasks" row
+// above the projects.
+type row struct {
+	node  *app.ProjectNode
+	depth int
+}
+
+func (r row) path() string {
+	if r.node == nil {
+		return ""
+	}
+	return r.node.Path
+}
+
+type model struct {
+	store     app.TaskStore
+	tree      []*app.ProjectNode
+	rows      []row
+	open      int             // open tasks in the store
+	collapsed map[string]bool // paths of collapsed projects
+	cursor    int             // index in rows
+	offset    int             // index of the first row shown
+	selected  string          // path of the project the task list is scoped to
+	focused   bool
+	width     int
+	height    int
+}
+
+func (m model) Init() tea.Cmd { return nil }
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.scroll()
+
+	case app.StoreChangedMsg, app.EventsMsg:
+		m.rebuild()
+
+	case app.ListSwitchedMsg:
+		m.store = msg.Store
+		m.collapsed = map[string]bool{}
+		m.cursor, m.selected = 0, ""
+		m.rebuild()
+
+	case app.DaySelectedMsg:
+		// the task list shows the day instead
+		m.selected = ""
+
+	case FocusMsg:
+		m.focused = msg.Focused
+
+	case tea.KeyMsg:
+		if !m.focused {
+			return m, nil
+		}
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "p":
+		return m, focus(false)
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.rows)-1 {
+			m.cursor++
+		}
+	case "left", "h":
+		// collapse the project, or move up to its parent
+		r := m.rows[m.cursor]
+		if r.node != nil && len(r.node.Children) > 0 && !m.collapsed[r.node.Path] {
+			m.collapsed[r.node.Path] = true
+			m.rebuild()
+		} else if i := strings.LastIndex(r.path(), "."); i != -1 {
+			m.cursor = m.find(r.path()[:i])
+		}
+	case "right", "l":
+		if r := m.rows[m.cursor]; r.node != nil && m.collapsed[r.node.Path] {
+			delete(m.collapsed, r.node.Path)
+			m.rebuild()
+		}
+	case "enter", " ":
+		m.selected = m.rows[m.cursor].path()
+		path := m.selected
+		return m, tea.Batch(
+			func() tea.Msg { return app.ProjectSelectedMsg{Path: path} },
+			focus(false),
+		)
+	}
+	m.scroll()
+	return m, nil
+}
+
+func focus(focused bool) tea.Cmd {
+	return func() tea.Msg { return FocusMsg{Focused: focused} }
+}
+
+// rebuild reads the projects from the store, keeping the cursor on the
+// project it was on.
+func (m *model) rebuild() {
+	path := ""
+	if m.cursor < len(m.rows) {
+		path = m.rows[m.cursor].path()
+	}
+
+	m.tree = app.ProjectTree(m.store.ListProject(""))
+	m.open = len(m.store.ListOpen())
+	m.rows = []row{{}}
+	for _, n := range m.tree {
+		n.Walk(func(n *app.ProjectNode, depth int) bool {
+			m.rows = append(m.rows, row{node: n, depth: depth})
+			return !m.collapsed[n.Path]
+		})
+	}
+	m.cursor = m.find(path)
+	m.scroll()
+}
+
+// find returns the row of the project with the given path, or of the
+// closest project above it that is shown.
+func (m model) find(path string) int {
+	for path != "" {
+		for i, r := range m.rows {
+			if r.path() == path {
+				return i
+			}
+		}
+		i := strings.LastIndex(path, ".")
+		if i == -1 {
+			break
+		}
+		path = path[:i]
+	}
+	return 0
+}
+
+// visible is how many rows fit in the pane.
+func (m model) visible() int {
+	return utils.MaxInt(m.height-2-3, 1) // border, header
+}
+
+// scroll keeps the cursor in view.
+func (m *model) scroll() {
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+m.visible() {
+		m.offset = m.cursor - m.visible() + 1
+	}
+	m.offset = utils.MaxInt(utils.MinInt(m.offset, len(m.rows)-m.visible()), 0)
+}
+
+func (m model) View() string {
+	width := m.width - 2
+	var b strings.Builder
+	last := utils.MinInt(m.offset+m.visible(), len(m.rows))
+	for i := m.offset; i < last; i++ {
+		b.WriteString(m.line(i, width) + "\n")
+	}
+	if len(m.tree) == 0 {
+		b.WriteString(emptyStyle.Render(" (no projects yet)") + "\n")
+	}
+
+	style := baseStyle
+	if m.focused {
+		style = style.BorderForeground(config.COLOR_HIGHLIGHT)
+	}
+	return style.Width(width).Height(m.height - 2).Render(
+		lipgloss.JoinVertical(lipgloss.Left,
+			lipgloss.PlaceHorizontal(width, lipgloss.Center, subHeader.Render("PROJECTS")),
+			b.String(),
+		),
+	)
+}
+
+// line renders row i: the project, indented by its depth, with a marker
+// for projects that have subprojects, and the number of its open tasks.
+func (m model) line(i, width int) string {
+	r := m.rows[i]
+	label, count := " All tasks", fmt.Sprint(m.open)
+	if r.node != nil {
+		marker := "  "
+		if len(r.node.Children) > 0 {
+			marker = "▾ "
+			if m.collapsed[r.node.Path] {
+				marker = "▸ "
+			}
+		}
+		label, count = " "+strings.Repeat("  ", r.depth)+marker, fmt.Sprint(r.node.Open)
+		label += utils.Truncate(r.node.Name, utils.MaxInt(width-lipgloss.Width(label)-len(count)-2, 1))
+	}
+	pad := strings.Repeat(" ", utils.MaxInt(width-lipgloss.Width(label)-len(count)-1, 1))
+
+	switch {
+	case i == m.cursor && m.focused:
+		return cursorStyle.Render(label + pad + count + " ")
+	case r.path() == m.selected:
+		return selectedStyle.Render(label) + pad + countStyle.Render(count) + " "
+	}
+	return label + pad + countStyle.Render(count) + " "
+}
+
+// New creates the projects pane over store.
+func New(store app.TaskStore) *model {
+	m := &model{store: store, collapsed: map[string]bool{}}
+	m.rebuild()
+	return m
+}
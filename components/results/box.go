@@ -1,14 +1,20 @@
 package results
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"time"
 
 	"strings"
 	"taskman/app"
+	"taskman/components/archive"
+	"taskman/components/changes"
 	"taskman/components/config"
 	"taskman/components/popup"
+	"taskman/components/tags"
+	"taskman/components/trash"
+	"taskman/utils"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -32,22 +38,27 @@ const (
 )
 
 type row struct {
-	kind  rowKind
-	label string
-	id    int // only for rowItem
+	kind     rowKind
+	label    string
+	id       int  // only for rowItem
+	archived bool // read-only, kept in the archive rather than the store
 }
 
 // ----- model -----
 type model struct {
 	day           time.Time
-	store         *app.Store
+	store         app.TaskStore
+	history       *app.History // wraps store; every mutation goes through it
 	rows          []row
 	cursor        int // index in rows (can land on headers; movement skips them)
 	width         int
 	height        int
 	err           error
 	loading       bool
-	pendingDelete *int // ID of task pending deletion, nil if no pending delete
+	pendingDelete *int   // ID of task pending deletion, nil if no pending delete
+	follow        int    // ID of a task added here, to move the cursor to once it shows up
+	tag           string // only tasks with this tag are shown, if set
+	project       string // shows the tasks of this project across all days instead of the day, if set
 	popup         tea.Model
 }
 
@@ -58,17 +69,55 @@ func (m model) getFadedView() string {
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
 	// Handle ChoiceResultMsg from popup first, regardless of popup state
 	if _, ok := msg.(popup.ChoiceResultMsg); ok {
 		// This is a result from the popup, handle it in the results model
+	} else if isStoreChange(msg) {
+		// The tasks changed, rebuild rows even behind a popup
+		if m.popup != nil {
+			m.popup, cmd = m.popup.Update(msg)
+		}
+	} else if _, ok := msg.(trash.ClosedMsg); ok {
+		// The trash view closed itself
+	} else if _, ok := msg.(archive.ClosedMsg); ok {
+		// The archive view closed itself
+	} else if _, ok := msg.(changes.ClosedMsg); ok {
+		// The history view closed itself
+	} else if _, ok := msg.(tags.ClosedMsg); ok {
+		// The tag picker closed itself
+	} else if _, ok := msg.(tags.SelectedMsg); ok {
+		// A tag was picked to filter by
+	} else if _, ok := msg.(app.ListSwitchedMsg); ok {
+		// A popup belongs to the previous list
+		m.popup, m.pendingDelete = nil, nil
 	} else if m.popup != nil {
 		// If there's a popup and it's not a ChoiceResultMsg, let the popup handle it
-		var cmd tea.Cmd
 		m.popup, cmd = m.popup.Update(msg)
 		return m, cmd
 	}
 
 	switch msg := msg.(type) {
+	case app.StoreChangedMsg, app.EventsMsg:
+		// keep the cursor on the same task across the change, or move it
+		// to the task just added
+		id := m.selectedID()
+		m.rebuildRows()
+		if i := m.findRowByID(m.follow); i != -1 {
+			m.cursor, m.follow = i, 0
+		} else if i := m.findRowByID(id); i != -1 {
+			m.cursor = i
+		}
+
+	case app.ListSwitchedMsg:
+		m.store = msg.Store
+		m.history = app.NewHistory(msg.Store, app.DefaultHistoryLimit)
+		m.err, m.tag, m.project = nil, "", ""
+		// start at the first task, or on the first header if there is none
+		m.cursor = -1
+		m.rebuildRows()
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
@@ -76,35 +125,72 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case app.DaySelectedMsg:
 		m.day = msg.Day
+		m.project = ""
 		m.cursor = 0
 		m.rebuildRows()
 
+	case app.ProjectSelectedMsg:
+		m.project = msg.Path
+		m.cursor = -1
+		m.rebuildRows()
+
 	case app.TaskFormResultMsg:
 		if msg.Result {
 			// add new task
 			if strings.TrimSpace(msg.Title) != "" {
-				if _, err := m.store.Add(msg.Title, msg.Notes, nil, m.day); err != nil {
-					m.err = err
+				var opts app.UpdateOptions
+				if len(msg.Tags) > 0 {
+					opts.Tags = &msg.Tags
+				}
+				if msg.Priority != app.PriorityNone {
+					opts.Priority = &msg.Priority
 				}
-				m.rebuildRows()
-				// move cursor to new item
-				m.cursor = m.findRowByID(m.store.NextID - 1)
-				if m.cursor == -1 {
-					m.cursor = m.nextSelectable(-1, +1)
+				if msg.Project != "" {
+					opts.Project = &msg.Project
+				}
+				t, err := m.history.AddWith(msg.Title, msg.Notes, nil, m.day, opts)
+				if err != nil {
+					m.setErr(err)
+					m.rebuildRows()
+				} else if m.shows(t) {
+					m.follow = t.ID
 				}
 			}
 		}
 
+	case trash.ClosedMsg:
+		m.popup = nil
+		m.rebuildRows()
+
+	case archive.ClosedMsg:
+		m.popup = nil
+		m.rebuildRows()
+		if !msg.Day.IsZero() {
+			day := msg.Day
+			cmd = func() tea.Msg { return app.DaySelectedMsg{Day: day} }
+		}
+
+	case changes.ClosedMsg:
+		m.popup = nil
+
+	case tags.ClosedMsg:
+		m.popup = nil
+
+	case tags.SelectedMsg:
+		m.popup = nil
+		m.tag = msg.Tag
+		m.cursor = -1
+		m.rebuildRows()
+
 	case popup.ChoiceResultMsg:
 		if msg.ID == "delete" && m.pendingDelete != nil {
 			if msg.Result {
-				// User confirmed deletion
-				if err := m.store.Delete(*m.pendingDelete); err != nil {
-					m.err = err
-				}
-				// move cursor up first so it feels natural
+				// User confirmed deletion; move cursor up first so it feels natural
 				m.cursor = m.nextSelectable(m.cursor, -1)
-				m.rebuildRows()
+				if err := m.history.Delete(*m.pendingDelete); err != nil {
+					m.setErr(err)
+					m.rebuildRows()
+				}
 			}
 			m.pendingDelete = nil
 		}
@@ -118,20 +204,19 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.cursor = m.nextSelectable(m.cursor, +1)
 		case " ", "enter":
 			// toggle completion on selected row
-			if m.rows[m.cursor].kind == rowItem {
-				id := m.rows[m.cursor].id
-				if _, err := m.store.ToggleCompleted(id); err != nil {
-					m.err = err
-				}
-				m.rebuildRows()
-				// after rebuild, attempt to keep cursor on same id
-				m.cursor = m.findRowByID(id)
-				if m.cursor == -1 {
-					m.cursor = m.nextSelectable(-1, +1)
+			if m.rows[m.cursor].archived {
+				cmd = archivedStatus
+			} else if m.rows[m.cursor].kind == rowItem {
+				// the cursor follows the task when its event arrives
+				if _, err := m.history.ToggleCompleted(m.rows[m.cursor].id); err != nil {
+					m.setErr(err)
+					m.rebuildRows()
 				}
 			}
 		case "d":
-			if m.rows[m.cursor].kind == rowItem {
+			if m.rows[m.cursor].archived {
+				cmd = archivedStatus
+			} else if m.rows[m.cursor].kind == rowItem {
 				id := m.rows[m.cursor].id
 				m.pendingDelete = &id
 				// Get task title for better confirmation message
@@ -142,10 +227,90 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				question := fmt.Sprintf("Are you sure you want to delete %s?", taskTitle)
 				m.popup = popup.NewChoice("delete", m.getFadedView(), m.width, question, false)
 			}
+		case "T":
+			if t, ok := m.store.(app.Trasher); ok {
+				m.popup = trash.New(t, m.getFadedView(), m.width)
+			}
+		case "A":
+			if a, ok := m.store.(app.Archiver); ok {
+				m.popup = archive.New(a, m.getFadedView(), m.width)
+			}
+		case "H":
+			if m.rows[m.cursor].archived {
+				cmd = archivedStatus
+			} else if m.rows[m.cursor].kind == rowItem {
+				if t, err := m.store.Get(m.rows[m.cursor].id); err != nil {
+					m.setErr(err)
+				} else {
+					m.popup = changes.New(m.store, t, m.getFadedView(), m.width)
+				}
+			}
+		case "+", "-":
+			if m.rows[m.cursor].archived {
+				cmd = archivedStatus
+			} else if m.rows[m.cursor].kind == rowItem {
+				cmd = m.reprioritize(m.rows[m.cursor].id, msg.String() == "+")
+			}
+		case "t":
+			m.popup = tags.New(m.store, m.tag, m.getFadedView(), utils.MinInt(m.width, 60))
+		case "u":
+			cmd = m.revert("undid", m.history.Undo)
+		case "ctrl+r":
+			cmd = m.revert("redid", m.history.Redo)
 		}
 	}
 
-	return m, nil
+	return m, cmd
+}
+
+// reprioritize raises or lowers the priority of task id by one level.
+func (m *model) reprioritize(id int, raise bool) tea.Cmd {
+	t, err := m.store.Get(id)
+	if err != nil {
+		m.setErr(err)
+		return nil
+	}
+	p := t.Priority.Lower()
+	if raise {
+		p = t.Priority.Raise()
+	}
+	if p == t.Priority {
+		return app.Status("priority is already " + p.String())
+	}
+	// the cursor follows the task to its new place when its event arrives
+	if _, err := m.history.Update(id, app.UpdateOptions{Priority: &p}); err != nil {
+		m.setErr(err)
+		m.rebuildRows()
+		return nil
+	}
+	return app.Status(fmt.Sprintf("priority of %q is %s", t.Title, p))
+}
+
+var archivedStatus = app.Status("archived tasks are read-only")
+
+// isStoreChange reports whether msg says the tasks have changed, either
+// underneath us or through the store.
+func isStoreChange(msg tea.Msg) bool {
+	switch msg.(type) {
+	case app.StoreChangedMsg, app.EventsMsg:
+		return true
+	}
+	return false
+}
+
+// revert runs an undo or redo step and reports what was done in the footer.
+func (m *model) revert(done string, step func() (string, error)) tea.Cmd {
+	label, err := step()
+	switch {
+	case errors.Is(err, app.ErrNothingToUndo), errors.Is(err, app.ErrNothingToRedo):
+		return app.Status(err.Error())
+	case err != nil:
+		m.setErr(err)
+		m.rebuildRows()
+		return app.Status(err.Error())
+	}
+	m.err = nil
+	return app.Status(done + " " + label)
 }
 
 var (
@@ -170,11 +335,16 @@ func (m model) View() string {
 	var b strings.Builder
 
 	isToday := m.day.IsZero() || (m.day.Year() == time.Now().Year() && m.day.YearDay() == time.Now().YearDay())
-	if isToday {
-		b.WriteString(config.TopHeaderStyle.Render("TODAY'S TASKS"))
-	} else {
-		b.WriteString(config.TopHeaderStyle.Render(m.day.Format("Monday, January 2") + " TASKS"))
+	header := "TODAY'S TASKS"
+	if m.project != "" {
+		header = "PROJECT " + m.project
+	} else if !isToday {
+		header = m.day.Format("Monday, January 2") + " TASKS"
 	}
+	if m.tag != "" {
+		header += " #" + m.tag
+	}
+	b.WriteString(config.TopHeaderStyle.Render(header))
 
 	for i, r := range m.rows {
 		switch r.kind {
@@ -219,52 +389,64 @@ func (m *model) rebuildRows() {
 	// Check if we're viewing today's tasks
 	isToday := m.day.IsZero() || (m.day.Year() == time.Now().Year() && m.day.YearDay() == time.Now().YearDay())
 
-	if isToday {
-		// Get all tasks to find overdue ones and completed overdue tasks
-		allTasks := m.store.List()
-		today := time.Now().Truncate(24 * time.Hour)
-		for _, t := range allTasks {
-			if !t.IsCompleted() {
-				// Incomplete overdue tasks
-				taskDate := t.Date.Truncate(24 * time.Hour)
-				if taskDate.Before(today) {
-					overdue = append(overdue, t)
-				}
-			} else if t.CompletedAt != nil {
-				// Completed tasks from past dates that were completed today
-				completedDate := t.CompletedAt.Truncate(24 * time.Hour)
-				taskDate := t.Date.Truncate(24 * time.Hour)
-				if completedDate.Equal(today) && taskDate.Before(today) {
-					dones = append(dones, t)
-				}
+	if m.project != "" {
+		// The tasks of the project on every day, sorted like List
+		for _, t := range m.store.ListProject(m.project) {
+			if t.IsCompleted() {
+				dones = append(dones, t)
+			} else {
+				todos = append(todos, t)
 			}
 		}
-
-		// Sort overdue tasks by due date (nil due goes last), then by ID
-		sort.SliceStable(overdue, func(i, j int) bool {
-			a, b := overdue[i], overdue[j]
-			if a.Due == nil && b.Due != nil {
-				return false
+	} else if isToday {
+		// Incomplete tasks of past days, already sorted by priority and due date
+		now := time.Now()
+		overdue = m.store.Overdue(now)
+
+		// Completed tasks from past dates that were completed today
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		for _, t := range m.store.CompletedOn(now) {
+			if t.Date.Before(today) {
+				dones = append(dones, t)
 			}
-			if a.Due != nil && b.Due == nil {
-				return true
-			}
-			if a.Due != nil && b.Due != nil && !a.Due.Equal(*b.Due) {
-				return a.Due.Before(*b.Due)
-			}
-			return a.ID < b.ID
-		})
+		}
 	}
 
 	// Get tasks for the current day
-	for _, t := range m.store.ListByDate(m.day) {
-		if t.IsCompleted() {
+	if m.project == "" {
+		for _, t := range m.store.ListByDate(m.day) {
+			if t.IsCompleted() {
+				dones = append(dones, t)
+			} else {
+				todos = append(todos, t)
+			}
+		}
+	}
+
+	// Completed tasks of past days may have moved into the archive
+	archived := map[int]bool{}
+	if a, ok := m.store.(app.Archiver); ok && m.project == "" {
+		tasks, err := a.ArchivedByDate(m.day)
+		if err != nil {
+			m.err = err
+		}
+		for _, t := range tasks {
+			archived[t.ID] = true
 			dones = append(dones, t)
-		} else {
-			todos = append(todos, t)
 		}
 	}
 
+	// A store that failed to read shows what it could
+	if r, ok := m.store.(app.ReadErrorer); ok {
+		if err := r.ReadError(); err != nil {
+			m.err = err
+		}
+	}
+
+	if m.tag != "" {
+		overdue, todos, dones = withTag(overdue, m.tag), withTag(todos, m.tag), withTag(dones, m.tag)
+	}
+
 	// Sort completed tasks by completion time (newest first), then by ID
 	sort.SliceStable(dones, func(i, j int) bool {
 		a, b := dones[i], dones[j]
@@ -290,18 +472,40 @@ func (m *model) rebuildRows() {
 	}
 	rows = append(rows, row{kind: rowHeader, label: fmt.Sprintf(" Completed (%d)", len(dones))})
 	for _, t := range dones {
+		if archived[t.ID] {
+			rows = append(rows, row{kind: rowItem, id: t.ID, label: m.archivedLine(t), archived: true})
+			continue
+		}
 		rows = append(rows, row{kind: rowItem, id: t.ID, label: m.taskLine(t)})
 	}
 	m.rows = rows
-	// clamp cursor
+	// clamp cursor; with no tasks it rests on the first header
 	if m.cursor < 0 || m.cursor >= len(m.rows) {
-		m.cursor = m.nextSelectable(-1, +1)
+		m.cursor = utils.MaxInt(m.nextSelectable(-1, +1), 0)
 	}
 	// if landed on header, move to next selectable
 	if len(m.rows) > 0 && m.rows[m.cursor].kind == rowHeader {
 		m.cursor = m.nextSelectable(m.cursor, +1)
 	}
 }
+
+// shows reports whether t is one of the tasks the pane shows, given the
+// tag and project it is scoped to; the day is not checked.
+func (m *model) shows(t *app.Task) bool {
+	return (m.tag == "" || t.HasTag(m.tag)) && (m.project == "" || t.InProject(m.project))
+}
+
+// withTag returns the tasks of tasks that carry tag.
+func withTag(tasks []*app.Task, tag string) []*app.Task {
+	var out []*app.Task
+	for _, t := range tasks {
+		if t.HasTag(tag) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
 func (m *model) taskLine(t *app.Task) string {
 	return m.taskLineWithOverdue(t, false)
 }
@@ -335,10 +539,15 @@ func (m *model) taskLineWithOverdue(t *app.Task, isOverdue bool) string {
 	} else if completed {
 		date = t.CompletedAt.Format("2006-01-02 15:05")
 		title = titleStyle.Render(t.Title)
+	} else if m.project != "" {
+		// across days, the day of the task says more than its creation
+		date = dateStyle.Render(t.Date.Format("Mon 2006-01-02"))
+		title = titleStyle.Render(t.Title)
 	} else {
 		date = dateStyle.Render(t.CreatedAt.Format("2006-01-02 15:04"))
 		title = titleStyle.Render(t.Title)
 	}
+	title = config.PriorityMark(t.Priority) + " " + title + tagChips(t)
 
 	padding := m.width - lipgloss.Width(title) - lipgloss.Width(date) - 3 - len(indent)
 	if padding < 1 {
@@ -346,6 +555,26 @@ func (m *model) taskLineWithOverdue(t *app.Task, isOverdue bool) string {
 	}
 	return title + lipgloss.NewStyle().PaddingLeft(padding).Render(date)
 }
+
+// archivedLine renders an archived task like a completed one, marked as
+// archived.
+func (m *model) archivedLine(t *app.Task) string {
+	title := config.PriorityMark(t.Priority) + " " + titleStyle.Render(t.Title) + tagChips(t)
+	date := dateStyle.Render("archived") + " " + t.CompletedAt.Format("2006-01-02 15:04")
+	padding := utils.MaxInt(m.width-lipgloss.Width(title)-lipgloss.Width(date)-3-len(indent), 1)
+	return title + lipgloss.NewStyle().PaddingLeft(padding).Render(date)
+}
+
+// tagChips renders the tags of a task as coloured chips, to follow its
+// title.
+func tagChips(t *app.Task) string {
+	var b strings.Builder
+	for _, tag := range t.Tags {
+		b.WriteString(" " + config.TagStyle(tag).Render(tag))
+	}
+	return b.String()
+}
+
 func (m *model) nextSelectable(start, dir int) int {
 	i := start + dir
 	for i >= 0 && i < len(m.rows) {
@@ -372,6 +601,20 @@ func (m *model) nextSelectable(start, dir int) int {
 	return start
 }
 
+// setErr shows err in the pane. A conflict means another process changed
+// the task first, so the store is reloaded to show what is on disk now.
+func (m *model) setErr(err error) {
+	m.err = err
+	if !errors.Is(err, app.ErrConflict) {
+		return
+	}
+	if r, ok := m.store.(app.Reloader); ok {
+		if rerr := r.Reload(); rerr != nil {
+			m.err = rerr
+		}
+	}
+}
+
 func (m model) isInCompletedSection(i int) bool {
 	// find the closest header above this row
 	for j := i; j >= 0; j-- {
@@ -382,6 +625,14 @@ func (m model) isInCompletedSection(i int) bool {
 	return false
 }
 
+// selectedID returns the ID of the task under the cursor, or -1.
+func (m model) selectedID() int {
+	if m.cursor >= 0 && m.cursor < len(m.rows) && m.rows[m.cursor].kind == rowItem {
+		return m.rows[m.cursor].id
+	}
+	return -1
+}
+
 func (m model) findRowByID(id int) int {
 	for i, r := range m.rows {
 		if r.kind == rowItem && r.id == id {
@@ -391,17 +642,13 @@ func (m model) findRowByID(id int) int {
 	return -1
 }
 
-func New() *model {
-	store, err := app.Load("todo-tasks.json")
-	if err != nil {
-		panic(err)
-	}
+func New(store app.TaskStore) *model {
 	m := &model{
-		store: store,
+		store:   store,
+		history: app.NewHistory(store, app.DefaultHistoryLimit),
 	}
+	// rebuilding places the cursor on the first selectable item
 	m.rebuildRows()
-	// place cursor on first selectable item
-	m.cursor = m.nextSelectable(-1, +1)
 	return m
 
 }
@@ -0,0 +1,54 @@
+package results
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"taskman/app"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestSwitchToEmptyList(t *testing.T) {
+	store := app.NewMemoryStore()
+	store.Add("a", "", nil, time.Now())
+	var m tea.Model = New(store)
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	m, _ = m.Update(app.ListSwitchedMsg{Name: "empty", Store: app.NewMemoryStore()})
+
+	for _, key := range []string{" ", "d", "H", "+", "-"} {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	}
+	if cursor := m.(model).cursor; cursor != 0 {
+		t.Errorf("cursor = %d, want 0, the first header", cursor)
+	}
+}
+
+// BenchmarkRebuildRows measures building the today view over a large store
+// of mostly completed tasks, a few of past days completed today.
+func BenchmarkRebuildRows(b *testing.B) {
+	now := time.Now()
+	tasks := make([]*app.Task, 100_000)
+	for i := range tasks {
+		date := now.AddDate(0, 0, i%2000-1000)
+		t := &app.Task{ID: i + 1, Date: date, Title: fmt.Sprintf("task %d", i+1), CreatedAt: date, UpdatedAt: date, Revision: 1}
+		switch {
+		case i%100 == 0:
+			t.CompletedAt = &now
+		case i%100 != 1:
+			t.CompletedAt = &date
+		}
+		tasks[i] = t
+	}
+	store := app.NewMemoryStore()
+	if err := store.ReplaceAll(tasks); err != nil {
+		b.Fatal(err)
+	}
+	m := New(store)
+	m.width, m.height = 100, 40
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.rebuildRows()
+	}
+}
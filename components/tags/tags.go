@@ -0,0 +1,111 @@
+package tags
+
+import (
+	"fmt"
+	"strings"
+
+	"taskman/app"
+	"taskman/components/config"
+	"taskman/components/overlay"
+	"taskman/utils"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	general = lipgloss.NewStyle().
+		Padding(0, 1, 0, 1).
+		Foreground(config.COLOR_FOREGROUND).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(config.COLOR_HIGHLIGHT)
+
+	selectedStyle = lipgloss.NewStyle().Background(config.COLOR_HIGHLIGHT).Foreground(config.COLOR_FOREGROUND).Bold(true)
+	countStyle    = lipgloss.NewStyle().Foreground(config.COLOR_LIGHTER)
+	helpStyle     = lipgloss.NewStyle().Foreground(config.COLOR_GRAY).MarginTop(1)
+)
+
+// ClosedMsg is sent when the picker is closed without choosing a tag.
+type ClosedMsg struct{}
+
+// SelectedMsg is sent when a tag is chosen to filter the tasks by. An
+// empty Tag means all tasks.
+type SelectedMsg struct {
+	Tag string
+}
+
+// Picker is a popup listing the tags of the tasks in the store, below an
+// entry for all tasks.
+type Picker struct {
+	tags   []app.TagCount
+	cursor int // 0 is "All tasks", i is tags[i-1]
+	bgRaw  string
+	width  int
+}
+
+// New creates a tag picker over bgRaw with the cursor on the current tag.
+func New(store app.TaskStore, current string, bgRaw string, width int) Picker {
+	p := Picker{tags: app.CountTags(store.List()), bgRaw: bgRaw, width: width}
+	for i, tc := range p.tags {
+		if tc.Tag == current {
+			p.cursor = i + 1
+		}
+	}
+	return p
+}
+
+// Init initializes the popup.
+func (p Picker) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages.
+func (p Picker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return p, nil
+	}
+	switch key.String() {
+	case "esc", "q", "t":
+		return p, func() tea.Msg { return ClosedMsg{} }
+	case "up", "k":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case "down", "j":
+		if p.cursor < len(p.tags) {
+			p.cursor++
+		}
+	case "enter":
+		tag := ""
+		if p.cursor > 0 {
+			tag = p.tags[p.cursor-1].Tag
+		}
+		return p, func() tea.Msg { return SelectedMsg{Tag: tag} }
+	}
+	return p, nil
+}
+
+// View renders the popup.
+func (p Picker) View() string {
+	width := p.width - 4
+	var b strings.Builder
+	item := func(i int, label, count string) {
+		line := " " + label
+		line += strings.Repeat(" ", utils.MaxInt(width-lipgloss.Width(line)-lipgloss.Width(count)-3, 1)) + count + " "
+		if i == p.cursor {
+			line = selectedStyle.Render(utils.RemoveANSI(line))
+		}
+		b.WriteString(line + "\n")
+	}
+	item(0, "All tasks", "")
+	for i, tc := range p.tags {
+		chip := config.TagStyle(tc.Tag).Render(utils.Truncate(tc.Tag, utils.MaxInt(width-12, 10)))
+		item(i+1, chip, countStyle.Render(fmt.Sprint(tc.Tasks)))
+	}
+
+	header := config.BoxHeader.Width(width).Render(fmt.Sprintf("Tags (%d)", len(p.tags)))
+	help := helpStyle.Render("enter filter • esc close")
+	ui := lipgloss.JoinVertical(lipgloss.Left, header, " ", b.String(), help)
+	return overlay.PlaceCenter(general.Width(width).Render(ui), p.bgRaw)
+}
@@ -0,0 +1,132 @@
+package trash
+
+import (
+	"fmt"
+	"strings"
+
+	"taskman/app"
+	"taskman/components/config"
+	"taskman/components/overlay"
+	"taskman/utils"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	general = lipgloss.NewStyle().
+		Padding(0, 1, 0, 1).
+		Foreground(config.COLOR_FOREGROUND).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(config.COLOR_HIGHLIGHT)
+
+	selectedStyle = lipgloss.NewStyle().Background(config.COLOR_HIGHLIGHT).Foreground(config.COLOR_FOREGROUND).Bold(true)
+	dateStyle     = lipgloss.NewStyle().Foreground(config.COLOR_LIGHTER)
+	helpStyle     = lipgloss.NewStyle().Foreground(config.COLOR_GRAY).MarginTop(1)
+)
+
+// ClosedMsg is sent when the trash view is closed. Restored tasks are not
+// reported one by one, so the parent should refresh on it.
+type ClosedMsg struct{}
+
+// View is a popup listing deleted tasks, with actions to restore or purge them.
+type View struct {
+	trash  app.Trasher
+	tasks  []*app.Task
+	cursor int
+	err    error
+	bgRaw  string
+	width  int
+}
+
+// New creates a trash view over bgRaw.
+func New(trash app.Trasher, bgRaw string, width int) View {
+	v := View{trash: trash, bgRaw: bgRaw, width: width}
+	v.refresh()
+	return v
+}
+
+// Init initializes the popup.
+func (v View) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages.
+func (v View) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case app.StoreChangedMsg, app.EventsMsg:
+		v.refresh()
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc", "q", "T":
+			return v, func() tea.Msg { return ClosedMsg{} }
+		case "up", "k":
+			if v.cursor > 0 {
+				v.cursor--
+			}
+		case "down", "j":
+			if v.cursor < len(v.tasks)-1 {
+				v.cursor++
+			}
+		case "r", "enter":
+			if t := v.selected(); t != nil {
+				_, v.err = v.trash.Restore(t.ID)
+				v.refresh()
+				if v.err == nil {
+					return v, app.Status(fmt.Sprintf("restored %q", t.Title))
+				}
+			}
+		case "x":
+			if t := v.selected(); t != nil {
+				v.err = v.trash.Purge(t.ID)
+				v.refresh()
+				if v.err == nil {
+					return v, app.Status(fmt.Sprintf("purged %q", t.Title))
+				}
+			}
+		}
+	}
+	return v, nil
+}
+
+// View renders the popup.
+func (v View) View() string {
+	width := v.width - 4
+	var b strings.Builder
+	if len(v.tasks) == 0 {
+		b.WriteString(config.EmptyMessageStyle.Render("Trash is empty."))
+	}
+	for i, t := range v.tasks {
+		date := dateStyle.Render(t.DeletedAt.Format("2006-01-02 15:04"))
+		title := utils.Truncate(t.Title, utils.MaxInt(width-lipgloss.Width(date)-4, 10))
+		padding := utils.MaxInt(width-lipgloss.Width(title)-lipgloss.Width(date)-2, 1)
+		line := " " + title + strings.Repeat(" ", padding) + date + " "
+		if i == v.cursor {
+			line = selectedStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+	if v.err != nil {
+		b.WriteString("\n" + config.ErrorStyle.Render("Error: "+v.err.Error()) + "\n")
+	}
+
+	header := config.BoxHeader.Width(width).Render(fmt.Sprintf("Trash (%d)", len(v.tasks)))
+	help := helpStyle.Render("r restore • x purge • esc close")
+	ui := lipgloss.JoinVertical(lipgloss.Left, header, " ", b.String(), help)
+	return overlay.PlaceCenter(general.Width(width).Render(ui), v.bgRaw)
+}
+
+func (v *View) refresh() {
+	v.tasks = v.trash.Trash()
+	if v.cursor >= len(v.tasks) {
+		v.cursor = utils.MaxInt(len(v.tasks)-1, 0)
+	}
+}
+
+func (v View) selected() *app.Task {
+	if v.cursor < len(v.tasks) {
+		return v.tasks[v.cursor]
+	}
+	return nil
+}
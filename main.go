@@ -1,10 +1,14 @@
 package main
 
 import (
+	"fmt"
+	"os"
 	"taskman/app"
 	"taskman/components/config"
 	"taskman/components/form"
+	"taskman/components/lists"
 	"taskman/components/popup"
+	"taskman/components/projects"
 	"taskman/utils"
 	"time"
 
@@ -42,7 +46,11 @@ func stripErr(n boxer.Node, _ error) boxer.Node {
 }
 
 func main() {
-	rootCmd.Execute()
+	// A failing exit status matters to callers like git, which runs
+	// "taskman merge" as a merge driver.
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
 }
 
 type Model struct {
@@ -51,10 +59,32 @@ type Model struct {
 	day    time.Time
 	width  int
 	height int
+	store  app.TaskStore
+	list   string              // name of the task list in store
+	watch  func(app.TaskStore) // reports changes of store by other processes
+	legacy string              // legacy task file to offer moving into store, if any
+
+	events      *app.EventQueue // changes made through store, for the panes
+	unsubscribe func()
+
+	project         string     // the task list is scoped to, if any; new tasks go in it
+	projectsFocused bool       // keys go to the projects pane only
+	projectsLeaf    boxer.Node // the projects pane, left out of narrow windows
 }
 
 func (m Model) Init() tea.Cmd {
-	return app.Today()
+	return tea.Batch(app.Today(), m.events.Next())
+}
+
+// subscribe queues the events of store, instead of the previous one's.
+func (m *Model) subscribe(store app.TaskStore) {
+	if m.unsubscribe != nil {
+		m.unsubscribe()
+		m.unsubscribe = nil
+	}
+	if p, ok := store.(app.Publisher); ok {
+		m.unsubscribe = p.Subscribe(m.events.Push)
+	}
 }
 
 func (m Model) GetFadedView() string {
@@ -77,12 +107,50 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.popup = nil
 			return m, nil
 		}
+		if msg.ID == "legacy" {
+			legacy := m.legacy
+			m.legacy, m.popup = "", nil
+			if !msg.Result {
+				return m, nil
+			}
+			n, err := importLegacy(m.store, legacy)
+			if err != nil {
+				return m, app.Status("Could not move tasks: " + err.Error())
+			}
+			return m, app.Status(fmt.Sprintf("Moved %d tasks from %s", n, legacy))
+		}
 
 	case app.TaskFormResultMsg:
 		m.popup = nil
 
+	case lists.ClosedMsg:
+		m.popup = nil
+		return m, nil
+
+	case app.ListSelectedMsg:
+		m.popup = nil
+		return m.switchList(msg.Name)
+
 	case app.DaySelectedMsg:
 		m.day = msg.Day
+		m.project = ""
+
+	case app.ProjectSelectedMsg:
+		m.project = msg.Path
+
+	case projects.FocusMsg:
+		m.projectsFocused = msg.Focused
+
+	case app.StoreChangedMsg, app.StatusMsg, app.EventsMsg:
+		// Panes refresh even while a popup is open.
+		for key, element := range m.tui.ModelMap {
+			m.tui.ModelMap[key], cmd = element.Update(msg)
+			cmds = append(cmds, cmd)
+		}
+		if _, ok := msg.(app.EventsMsg); ok {
+			cmds = append(cmds, m.events.Next())
+		}
+		return m, tea.Batch(cmds...)
 
 	case tea.KeyMsg:
 		{
@@ -99,11 +167,30 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "a":
 				if m.popup == nil {
 					f := form.NewTaskForm(m.GetFadedView(), m.width-4, m.tui.LayoutTree.GetWidth())
+					f.SetProject(m.project)
 					m.popup = f
 
 					return m, m.popup.Init()
 				}
 
+			case "L":
+				if m.popup == nil {
+					names, err := app.Lists(baseStorePath())
+					if err != nil {
+						return m, app.Status("Could not list task lists: " + err.Error())
+					}
+					m.popup = lists.New(names, m.list, m.GetFadedView(), utils.MinInt(m.width-4, 60))
+					return m, m.popup.Init()
+				}
+
+			case "p":
+				if m.popup == nil && !m.projectsFocused {
+					if m.width < projectsMinWidth {
+						return m, app.Status(fmt.Sprintf("Widen the window to %d columns to see the projects", projectsMinWidth))
+					}
+					return m, func() tea.Msg { return projects.FocusMsg{Focused: true} }
+				}
+
 			case "]":
 				if m.popup == nil {
 					return m, app.NextDay(m.day)
@@ -120,8 +207,19 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		m.layout()
 		m.tui.UpdateSize(msg)
 
+		// Offer once, as soon as there is a screen to draw the popup on.
+		if m.legacy != "" && m.popup == nil {
+			question := fmt.Sprintf("Move the tasks in %s to %s?", m.legacy, storePath())
+			m.popup = popup.NewChoice("legacy", m.GetFadedView(), m.width-4, question, true)
+			return m, m.popup.Init()
+		}
+		// The projects pane is left out of a narrow window
+		if m.projectsFocused && m.width < projectsMinWidth {
+			return m, func() tea.Msg { return projects.FocusMsg{Focused: false} }
+		}
 		return m, nil
 	}
 
@@ -129,6 +227,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if m.popup != nil {
 		m.popup, cmd = m.popup.Update(msg)
 		cmds = append(cmds, cmd)
+	} else if _, ok := msg.(tea.KeyMsg); ok && m.projectsFocused {
+		m.tui.ModelMap["projects"], cmd = m.tui.ModelMap["projects"].Update(msg)
+		cmds = append(cmds, cmd)
 	} else {
 		for key, element := range m.tui.ModelMap {
 			m.tui.ModelMap[key], cmd = element.Update(msg)
@@ -139,8 +240,56 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// switchList opens the named task list, creating it if needed, and shows
+// it in every pane.
+func (m Model) switchList(name string) (tea.Model, tea.Cmd) {
+	if name == m.list {
+		return m, nil
+	}
+	store, err := openList(name)
+	if err != nil {
+		return m, app.Status(fmt.Sprintf("Could not open list %q: %v", name, err))
+	}
+	closeStore(m.store)
+	m.store, m.list, m.project = store, name, ""
+	m.subscribe(store)
+	if m.watch != nil {
+		m.watch(store)
+	}
+
+	var cmd tea.Cmd
+	cmds := []tea.Cmd{app.Status(fmt.Sprintf("Switched to list %q", name))}
+	msg := app.ListSwitchedMsg{Name: name, Store: store}
+	for key, element := range m.tui.ModelMap {
+		m.tui.ModelMap[key], cmd = element.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// The smallest window the TUI is drawn in, and the width from which the
+// projects pane is shown between the task list and the calendar.
+const (
+	minWidth         = 40
+	minHeight        = 30
+	projectsMinWidth = 120
+)
+
+// layout puts the projects pane between the task list and the calendar
+// when the window is wide enough for it, and leaves it out otherwise.
+func (m *Model) layout() {
+	center := &m.tui.LayoutTree.Children[0]
+	shown := len(center.Children) == 3
+	switch wide := m.width >= projectsMinWidth; {
+	case wide && !shown:
+		center.Children = []boxer.Node{center.Children[0], m.projectsLeaf, center.Children[1]}
+	case !wide && shown:
+		center.Children = []boxer.Node{center.Children[0], center.Children[2]}
+	}
+}
+
 func (m Model) SizeIsTooSmall() bool {
-	return m.width < 40 || m.height < 30
+	return m.width < minWidth || m.height < minHeight
 }
 
 func (m Model) View() string {
@@ -152,7 +301,7 @@ func (m Model) View() string {
 				lipgloss.JoinVertical(
 					lipgloss.Left,
 					config.BoxHeader.Render("Taskman "+version),
-					"Please resize the window to at least 140x30"),
+					fmt.Sprintf("Please resize the window to at least %dx%d", minWidth, minHeight)),
 			)
 	}
 